@@ -7,17 +7,26 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"netcheck/internal/checks"
 	"netcheck/internal/compare"
 	"netcheck/internal/config"
 	"netcheck/internal/docs"
+	"netcheck/internal/eval"
 	"netcheck/internal/events"
 	"netcheck/internal/execx"
 	"netcheck/internal/exitcode"
 	"netcheck/internal/model"
 	"netcheck/internal/output"
+	"netcheck/internal/prom"
+	"netcheck/internal/rpm"
 	"netcheck/internal/runner"
+	"netcheck/internal/soak"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 )
@@ -33,7 +42,7 @@ func main() {
 
 func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer, ex execx.Executor) int {
 	if len(args) == 0 {
-		fmt.Fprintln(stderr, "usage: netcheck <run|soak|compare|man>")
+		fmt.Fprintln(stderr, "usage: netcheck <run|soak|exporter|serve-responsiveness|compare|explain-score|config|man>")
 		return exitcode.ConfigError
 	}
 	switch args[0] {
@@ -41,8 +50,16 @@ func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer, ex exe
 		return cmdRun(ctx, args[1:], stdout, stderr, ex)
 	case "soak":
 		return cmdSoak(ctx, args[1:], stdout, stderr, ex)
+	case "exporter":
+		return cmdExporter(ctx, args[1:], stderr, ex)
+	case "serve-responsiveness":
+		return cmdServeResponsiveness(ctx, args[1:], stderr)
 	case "compare":
 		return cmdCompare(args[1:], stdout, stderr)
+	case "explain-score":
+		return cmdExplainScore(args[1:], stdout, stderr)
+	case "config":
+		return cmdConfig(args[1:], stdout, stderr)
 	case "man":
 		return cmdMan(args[1:], stdout, stderr)
 	default:
@@ -54,7 +71,7 @@ func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer, ex exe
 func parseCommon(fs *flag.FlagSet) *model.RunOptions {
 	opts := &model.RunOptions{}
 	var labels, selectGroups, skipGroups string
-	fs.StringVar(&opts.Format, "format", "table", "table|json|jsonl|both")
+	fs.StringVar(&opts.Format, "format", "table", "table|json|jsonl|both|prom|junit")
 	fs.StringVar(&opts.OutPath, "out", "", "output file")
 	fs.BoolVar(&opts.Verbose, "verbose", false, "verbose output")
 	fs.BoolVar(&opts.Quiet, "quiet", false, "quiet output")
@@ -80,6 +97,7 @@ func cmdRun(ctx context.Context, args []string, stdout, stderr io.Writer, ex exe
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	opts := parseCommon(fs)
+	cf := addCassetteFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return exitcode.ConfigError
 	}
@@ -89,6 +107,11 @@ func cmdRun(ctx context.Context, args []string, stdout, stderr io.Writer, ex exe
 		fmt.Fprintln(stderr, "config error:", err)
 		return exitcode.ConfigError
 	}
+	ex, save, err := cf.executor(ex, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.ConfigError
+	}
 	effectiveTimeout := opts.TimeoutSec
 	minNeeded := estimateRunTimeoutSec(cfg, *opts)
 	if effectiveTimeout < minNeeded {
@@ -112,6 +135,10 @@ func cmdRun(ctx context.Context, args []string, stdout, stderr io.Writer, ex exe
 		rctx = execx.WithLogFunc(rctx, ui.OnExecLog)
 	}
 	result, err := runner.RunOnce(rctx, ex, cfg, *opts, version, commit)
+	if serr := save(); serr != nil {
+		fmt.Fprintln(stderr, "record error:", serr)
+		return exitcode.OutputError
+	}
 	if err != nil {
 		fmt.Fprintln(stderr, err)
 		return exitcode.RuntimeError
@@ -135,6 +162,8 @@ func cmdSoak(ctx context.Context, args []string, stdout, stderr io.Writer, ex ex
 	opts := parseCommon(fs)
 	intervalSec := fs.Int("interval", -1, "interval seconds")
 	durationSec := fs.Int("duration", -1, "duration seconds; 0 means until interrupted")
+	includeRaw := fs.Bool("raw", false, "attach raw tool output to check_result events")
+	cf := addCassetteFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return exitcode.ConfigError
 	}
@@ -147,6 +176,17 @@ func cmdSoak(ctx context.Context, args []string, stdout, stderr io.Writer, ex ex
 		fmt.Fprintln(stderr, "config error:", err)
 		return exitcode.ConfigError
 	}
+	ex, save, err := cf.executor(ex, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.ConfigError
+	}
+	// A soak usually ends on a timeout or interrupt; keep whatever was recorded.
+	defer func() {
+		if err := save(); err != nil {
+			fmt.Fprintln(stderr, "record error:", err)
+		}
+	}()
 	interval := *intervalSec
 	duration := *durationSec
 	if interval < 0 {
@@ -187,41 +227,257 @@ func cmdSoak(ctx context.Context, args []string, stdout, stderr io.Writer, ex ex
 	}
 	_ = ew.Emit("run_started", opts.RunID, map[string]any{"command": "soak"})
 	start := time.Now()
-	lastExit := 0
+	n := 0
+	agg := soak.NewAggregator()
+	outages := soak.NewOutageTracker()
+	latest := map[string]model.Status{}
+	sched := soak.NewSchedule(start, selectedGroups(cfg, *opts), time.Duration(interval)*time.Second, cfg.Soak.Schedules)
+	// wctx ends at --duration as well as on the global timeout, so a long
+	// group schedule cannot sleep past the end of the soak. Windows already
+	// running still get sctx.
+	wctx := sctx
+	if duration > 0 {
+		var cancel context.CancelFunc
+		wctx, cancel = context.WithDeadline(sctx, start.Add(time.Duration(duration)*time.Second))
+		defer cancel()
+	}
 	for {
-		if duration > 0 && time.Since(start) > time.Duration(duration)*time.Second {
+		if err := wctx.Err(); err != nil {
 			break
 		}
-		if err := sctx.Err(); err != nil {
+		due := sched.Due(time.Now())
+		if len(due) == 0 {
 			break
 		}
-		res, err := runner.RunOnce(sctx, ex, cfg, *opts, version, commit)
+		window := *opts
+		window.Select = due
+		res, err := runner.RunOnce(sctx, ex, cfg, window, version, commit)
 		if err != nil {
 			_ = ew.Emit("run_finished", opts.RunID, map[string]any{"error": err.Error()})
 			return exitcode.RuntimeError
 		}
+		n++
+		agg.Add(res.Report)
+		for _, c := range res.Report.Checks {
+			_ = ew.Emit("check_result", opts.RunID, events.CheckResult(n, c, *includeRaw))
+		}
 		for _, c := range res.Report.Checks {
-			_ = ew.Emit("check_result", opts.RunID, map[string]any{"id": c.ID, "status": c.Status, "target": c.Target})
+			latest[c.ID] = c.Status
+		}
+		_ = ew.Emit("interval_summary", opts.RunID, map[string]any{"interval": n, "groups": due, "summary": res.Report.Summary, "score": res.Report.Score})
+		started, ended := outages.Observe(res.Report)
+		if started != nil {
+			p, _ := events.Payload(started)
+			_ = ew.Emit("outage_started", opts.RunID, p)
+		}
+		if ended != nil {
+			p, _ := events.Payload(ended)
+			_ = ew.Emit("outage_ended", opts.RunID, p)
 		}
-		_ = ew.Emit("interval_summary", opts.RunID, map[string]any{"summary": res.Report.Summary, "score": res.Report.Score})
 		if opts.Verbose && !opts.Quiet {
 			fmt.Fprintf(stderr, "\n[SOAK] op : interval summary score=%d pass=%d warn=%d fail=%d skip=%d\n", res.Report.Score, res.Report.Summary.Pass, res.Report.Summary.Warn, res.Report.Summary.Fail, res.Report.Summary.Skip)
 		}
-		lastExit = exitcode.FromSummary(res.Report.Summary, opts.StrictWarn)
 		if !opts.Quiet && (opts.Format == "both" || opts.Format == "table") {
 			_ = output.WriteTableWithOptions(stdout, res.Report, output.TableOptions{Color: shouldColorize(stdout, opts.NoColor)})
 		}
 		select {
-		case <-time.After(time.Duration(interval) * time.Second):
-		case <-sctx.Done():
-			// global timeout/cancel.
+		case <-time.After(time.Until(sched.Next())):
+		case <-wctx.Done():
+			// duration reached or global timeout/cancel.
 		}
 	}
 	if cfg.Soak.EmitFinalSummary {
-		_ = ew.Emit("run_summary", opts.RunID, map[string]any{"done": true})
+		sum := agg.Summary()
+		_ = ew.Emit("run_summary", opts.RunID, map[string]any{"done": true, "intervals": sum.Intervals, "worst_interval": sum.WorstInterval, "checks": sum.Checks, "outages": outages.Summary(time.Now().UTC())})
 	}
 	_ = ew.Emit("run_finished", opts.RunID, map[string]any{"duration_sec": int(time.Since(start).Seconds())})
-	return lastExit
+	// Groups run on different schedules, so the exit code reflects the most
+	// recent result of every check rather than only the last window.
+	var final model.Summary
+	for _, st := range latest {
+		final.Add(st)
+	}
+	return exitcode.FromSummary(final, opts.StrictWarn)
+}
+
+type cassetteFlags struct {
+	record string
+	replay string
+}
+
+func addCassetteFlags(fs *flag.FlagSet) *cassetteFlags {
+	cf := &cassetteFlags{}
+	fs.StringVar(&cf.record, "record", "", "record every command to a cassette file")
+	fs.StringVar(&cf.replay, "replay", "", "replay commands from a cassette file instead of running them")
+	return cf
+}
+
+// executor wraps ex for --record or swaps it for --replay. save writes the
+// recording and is a no-op otherwise.
+func (cf *cassetteFlags) executor(ex execx.Executor, stderr io.Writer) (execx.Executor, func() error, error) {
+	noop := func() error { return nil }
+	switch {
+	case cf.record != "" && cf.replay != "":
+		return nil, nil, errors.New("--record and --replay cannot be combined")
+	case cf.replay != "":
+		rp, err := execx.LoadCassette(cf.replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rp.GOOS() != runtime.GOOS {
+			fmt.Fprintf(stderr, "replay: cassette was recorded on %s; local discovery follows the recording\n", rp.GOOS())
+		}
+		return rp, noop, nil
+	case cf.record != "":
+		rec := execx.NewRecorder(ex)
+		return rec, func() error { return rec.Save(cf.record) }, nil
+	}
+	return ex, noop, nil
+}
+
+// selectedGroups lists the groups of the checks a run would select, in check order.
+func selectedGroups(cfg config.Config, opts model.RunOptions) []string {
+	var out []string
+	for _, c := range runner.SelectedChecks(cfg, opts) {
+		if !slices.Contains(out, c.Group()) {
+			out = append(out, c.Group())
+		}
+	}
+	return out
+}
+
+func cmdExporter(ctx context.Context, args []string, stderr io.Writer, ex execx.Executor) int {
+	fs := flag.NewFlagSet("exporter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts := &model.RunOptions{}
+	var labels, selectGroups, skipGroups string
+	listen := fs.String("listen", ":9469", "address serving /metrics")
+	intervalSec := fs.Int("interval", -1, "interval seconds")
+	durationSec := fs.Int("duration", 0, "duration seconds; 0 means until interrupted")
+	fs.StringVar(&opts.ConfigPath, "config", "", "config path")
+	fs.StringVar(&opts.RunID, "id", "", "run id")
+	fs.StringVar(&labels, "labels", "", "labels key=value,key2=value2 (exported as constant labels)")
+	fs.StringVar(&selectGroups, "select", "", "comma-separated group filter")
+	fs.StringVar(&skipGroups, "skip", "", "comma-separated group skip")
+	if err := fs.Parse(args); err != nil {
+		return exitcode.ConfigError
+	}
+	opts.Select = splitCSV(selectGroups)
+	opts.Skip = splitCSV(skipGroups)
+	opts.Labels = parseLabels(labels)
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "config error:", err)
+		return exitcode.ConfigError
+	}
+	interval := *intervalSec
+	if interval < 0 {
+		interval = cfg.Soak.IntervalSec
+	}
+	if interval <= 0 {
+		interval = 1
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.RuntimeError
+	}
+	exp := prom.NewExporter()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exp)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+	fmt.Fprintf(stderr, "[EXPORTER] op : serving http://%s/metrics every %ds\n", ln.Addr(), interval)
+
+	sctx := ctx
+	if *durationSec > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(ctx, time.Duration(*durationSec)*time.Second)
+		defer cancel()
+	}
+	latest := map[string]model.CheckResult{}
+	sched := soak.NewSchedule(time.Now(), selectedGroups(cfg, *opts), time.Duration(interval)*time.Second, cfg.Soak.Schedules)
+	for sctx.Err() == nil {
+		due := sched.Due(time.Now())
+		if len(due) == 0 {
+			break
+		}
+		window := *opts
+		window.Select = due
+		rctx, cancel := context.WithTimeout(sctx, time.Duration(estimateRunTimeoutSec(cfg, window))*time.Second)
+		res, err := runner.RunOnce(rctx, ex, cfg, window, version, commit)
+		cancel()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitcode.RuntimeError
+		}
+		if sctx.Err() != nil {
+			break
+		}
+		// A window runs only the due groups; score the latest result of
+		// every check so the gauge does not swing with the schedule.
+		for _, c := range res.Report.Checks {
+			latest[c.ID] = c
+		}
+		all := make([]model.CheckResult, 0, len(latest))
+		for _, c := range latest {
+			all = append(all, c)
+		}
+		res.Report.Score = eval.Breakdown(all, cfg.Scoring, cfg.Thresholds.Limits).Score
+		exp.Observe(res.Report)
+		select {
+		case <-time.After(time.Until(sched.Next())):
+		case <-sctx.Done():
+		}
+	}
+	return exitcode.OK
+}
+
+func cmdServeResponsiveness(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve-responsiveness", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", ":4043", "address serving the responsiveness endpoints")
+	certFile := fs.String("tls-cert", "", "certificate file; without it the server speaks plain HTTP/2")
+	keyFile := fs.String("tls-key", "", "key file for --tls-cert")
+	durationSec := fs.Int("duration", 0, "duration seconds; 0 means until interrupted")
+	if err := fs.Parse(args); err != nil {
+		return exitcode.ConfigError
+	}
+	if fs.NArg() != 0 || (*certFile == "") != (*keyFile == "") {
+		fmt.Fprintln(stderr, "usage: netcheck serve-responsiveness [--listen addr] [--tls-cert file --tls-key file] [--duration sec]")
+		return exitcode.ConfigError
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.RuntimeError
+	}
+	srv := rpm.NewServer(ln.Addr().String())
+	defer srv.Close()
+	scheme := "http"
+	errc := make(chan error, 1)
+	if *certFile != "" {
+		scheme = "https"
+		go func() { errc <- srv.ServeTLS(ln, *certFile, *keyFile) }()
+	} else {
+		go func() { errc <- srv.Serve(ln) }()
+	}
+	fmt.Fprintf(stderr, "[RESPONSIVENESS] op : serving %s://%s/config\n", scheme, ln.Addr())
+
+	sctx := ctx
+	if *durationSec > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(ctx, time.Duration(*durationSec)*time.Second)
+		defer cancel()
+	}
+	select {
+	case <-sctx.Done():
+	case err := <-errc:
+		fmt.Fprintln(stderr, err)
+		return exitcode.RuntimeError
+	}
+	return exitcode.OK
 }
 
 func cmdCompare(args []string, stdout, stderr io.Writer) int {
@@ -229,14 +485,27 @@ func cmdCompare(args []string, stdout, stderr io.Writer) int {
 	fs.SetOutput(stderr)
 	format := fs.String("format", "table", "table|json")
 	out := fs.String("out", "", "optional output file")
+	var rawRules stringList
+	fs.Var(&rawRules, "max-regression", "metric=pct regression limit; repeatable or comma-separated")
 	if err := fs.Parse(args); err != nil {
 		return exitcode.ConfigError
 	}
 	rest := fs.Args()
 	if len(rest) != 2 {
-		fmt.Fprintln(stderr, "usage: netcheck compare <baseline.json> <candidate.json>")
+		fmt.Fprintln(stderr, "usage: netcheck compare [--max-regression metric=pct] <baseline.json> <candidate.json>")
 		return exitcode.ConfigError
 	}
+	var rules []compare.Rule
+	for _, raw := range rawRules {
+		for _, part := range splitCSV(raw) {
+			r, err := compare.ParseRule(part)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitcode.ConfigError
+			}
+			rules = append(rules, r)
+		}
+	}
 	before, err := compare.Load(rest[0])
 	if err != nil {
 		fmt.Fprintln(stderr, err)
@@ -247,7 +516,19 @@ func cmdCompare(args []string, stdout, stderr io.Writer) int {
 		fmt.Fprintln(stderr, err)
 		return exitcode.RuntimeError
 	}
+	if err := compare.CheckRules(rules, before, after); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.ConfigError
+	}
 	d := compare.Build(before, after)
+	regressions := d.Evaluate(rules)
+	for _, r := range regressions {
+		fmt.Fprintln(stderr, "regression:", r)
+	}
+	code := exitcode.OK
+	if len(regressions) > 0 {
+		code = exitcode.ChecksFailed
+	}
 	if *format == "json" {
 		b, _ := json.MarshalIndent(d, "", "  ")
 		if *out == "" {
@@ -258,7 +539,7 @@ func cmdCompare(args []string, stdout, stderr io.Writer) int {
 				return exitcode.OutputError
 			}
 		}
-		return 0
+		return code
 	}
 	path := *out
 	if path == "" {
@@ -274,9 +555,141 @@ func cmdCompare(args []string, stdout, stderr io.Writer) int {
 		return exitcode.OutputError
 	}
 	_, _ = stdout.Write(b)
+	return code
+}
+
+func cmdExplainScore(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("explain-score", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "table", "table|json")
+	if err := fs.Parse(args); err != nil {
+		return exitcode.ConfigError
+	}
+	if fs.NArg() != 1 || (*format != "table" && *format != "json") {
+		fmt.Fprintln(stderr, "usage: netcheck explain-score [--format table|json] <report.json>")
+		return exitcode.ConfigError
+	}
+	report, err := compare.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.RuntimeError
+	}
+	if report.ScoreBreakdown == nil {
+		b := recomputeBreakdown(report)
+		report.Score = b.Score
+		report.ScoreBreakdown = &b
+	}
+	if *format == "json" {
+		b, err := json.MarshalIndent(report.ScoreBreakdown, "", "  ")
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitcode.OutputError
+		}
+		_, _ = stdout.Write(append(b, '\n'))
+		return exitcode.OK
+	}
+	if err := output.WriteScoreExplanation(stdout, report); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitcode.OutputError
+	}
+	return exitcode.OK
+}
+
+// recomputeBreakdown scores a report written before score_breakdown existed,
+// using its echoed scoring model and thresholds where present.
+func recomputeBreakdown(report model.Report) model.ScoreBreakdown {
+	scoring := eval.DefaultScoring()
+	if report.Scoring != nil {
+		scoring = *report.Scoring
+	}
+	th := config.Defaults().Thresholds
+	if raw, ok := report.Config["thresholds"]; ok {
+		if b, err := json.Marshal(raw); err == nil {
+			_ = json.Unmarshal(b, &th)
+		}
+	}
+	return eval.Breakdown(report.Checks, scoring, th.Limits)
+}
+
+// stringList collects a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func cmdConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(stderr, "usage: netcheck config validate [--config path | path]")
+		return exitcode.ConfigError
+	}
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", "", "config path")
+	format := fs.String("format", "table", "table|json")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return exitcode.ConfigError
+	}
+	if *path == "" && len(rest) == 1 {
+		*path = rest[0]
+	}
+	if *path == "" || len(rest) > 1 {
+		fmt.Fprintln(stderr, "usage: netcheck config validate [--config path | path]")
+		return exitcode.ConfigError
+	}
+	issues, err := config.Validate(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", *path, err)
+		return exitcode.ConfigError
+	}
+	if *format == "json" {
+		if issues == nil {
+			issues = []config.Issue{}
+		}
+		b, _ := json.MarshalIndent(map[string]any{"path": *path, "valid": len(issues) == 0, "issues": issues}, "", "  ")
+		_, _ = stdout.Write(append(b, '\n'))
+	} else {
+		for _, is := range issues {
+			if is.Line > 0 {
+				fmt.Fprintf(stdout, "%s:%d: %s\n", *path, is.Line, is.Message)
+			} else {
+				fmt.Fprintf(stdout, "%s: %s\n", *path, is.Message)
+			}
+		}
+		if len(issues) == 0 {
+			fmt.Fprintf(stdout, "%s: ok\n", *path)
+		}
+	}
+	if len(issues) > 0 {
+		return exitcode.ConfigError
+	}
 	return 0
 }
 
+// parseInterspersed parses flags on either side of positional arguments,
+// where fs.Parse alone stops at the first positional. "--" still ends flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return pos, nil
+		}
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(pos, rest...), nil
+		}
+		pos = append(pos, rest[0])
+		args = rest[1:]
+	}
+}
+
 func cmdMan(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("man", flag.ContinueOnError)
 	fs.SetOutput(stderr)
@@ -305,6 +718,12 @@ func cmdMan(args []string, stdout, stderr io.Writer) int {
 }
 
 func emitReport(report model.Report, opts model.RunOptions, stdout io.Writer) error {
+	if opts.Format == "prom" {
+		if opts.OutPath != "" {
+			return prom.WriteFileAtomic(opts.OutPath, report)
+		}
+		return prom.Write(stdout, prom.ReportFamilies(report, prom.ConstLabels(report.Labels)))
+	}
 	w, closeFn, err := outWriter(opts.OutPath, stdout)
 	if err != nil {
 		return err
@@ -322,6 +741,8 @@ func emitReport(report model.Report, opts model.RunOptions, stdout io.Writer) er
 		return err
 	case "table":
 		return output.WriteTableWithOptions(w, report, output.TableOptions{Color: shouldColorize(stdout, opts.NoColor)})
+	case "junit":
+		return output.WriteJUnit(w, report, opts.StrictWarn)
 	case "both":
 		if err := output.WriteTableWithOptions(w, report, output.TableOptions{Color: shouldColorize(stdout, opts.NoColor)}); err != nil {
 			return err
@@ -393,47 +814,100 @@ func parseLabels(raw string) map[string]string {
 	return out
 }
 
+// estimateRunTimeoutSec sizes the run deadline from the scheduler plan: exclusive
+// stages add up, while parallel stages cost their makespan on the worker pool.
 func estimateRunTimeoutSec(cfg config.Config, opts model.RunOptions) int {
-	checks := runner.SelectedChecks(cfg, opts)
+	workers := cfg.Concurrency.Workers
+	if workers < 1 {
+		workers = 1
+	}
 	total := 0
-	for _, c := range checks {
-		switch c.Group() {
-		case "local":
-			total += 14
-		case "reachability":
-			total += 12
-		case "dns":
-			total += 3
-		case "http":
-			total += 8
-		case "path":
-			total += 12
-		case "bufferbloat":
-			if cfg.Bandwidth.Iperf.Enabled && cfg.Bandwidth.Iperf.Target != "" {
-				total += 30
-			} else if cfg.Bandwidth.Speedtest.Enabled {
-				total += 55
-			} else {
-				total += 25
-			}
-		case "bandwidth":
-			id := c.ID()
-			if strings.Contains(id, "speedtest") {
-				total += 50
-			}
-			if strings.Contains(id, "iperf") {
-				d := cfg.Bandwidth.Iperf.DurationSec
-				if d <= 0 {
-					d = 30
-				}
-				total += d + 15
-			}
-		default:
-			total += 5
+	for _, st := range runner.PlanStages(runner.SelectedChecks(cfg, opts)) {
+		if st.Exclusive {
+			total += estimateCheckSec(cfg, st.Checks[0])
+			continue
 		}
+		total += estimateStageSec(cfg, st.Checks, workers)
 	}
 	if total < 30 {
 		total = 30
 	}
 	return total
 }
+
+// estimateStageSec greedily assigns checks to the least-loaded worker, then
+// raises the result if a per-group limit would serialize that group further.
+func estimateStageSec(cfg config.Config, cs []checks.Check, workers int) int {
+	loads := make([]int, workers)
+	groupSum := map[string]int{}
+	for _, c := range cs {
+		est := estimateCheckSec(cfg, c)
+		groupSum[c.Group()] += est
+		least := 0
+		for i := range loads {
+			if loads[i] < loads[least] {
+				least = i
+			}
+		}
+		loads[least] += est
+	}
+	makespan := 0
+	for _, l := range loads {
+		if l > makespan {
+			makespan = l
+		}
+	}
+	for g, sum := range groupSum {
+		if limit := cfg.Concurrency.GroupLimits[g]; limit > 0 {
+			if bound := (sum + limit - 1) / limit; bound > makespan {
+				makespan = bound
+			}
+		}
+	}
+	return makespan
+}
+
+func estimateCheckSec(cfg config.Config, c checks.Check) int {
+	switch c.Group() {
+	case "local":
+		return 14
+	case "reachability":
+		return 12
+	case "dns":
+		return 3
+	case "http":
+		return 8
+	case "tls":
+		return 5
+	case "path":
+		return 12
+	case "bufferbloat":
+		// Idle ping, then download and upload phases with pings under load.
+		if cfg.Bandwidth.Iperf.Enabled && cfg.Bandwidth.Iperf.Target != "" {
+			return 42
+		} else if cfg.Bandwidth.Speedtest.Enabled {
+			return 75
+		}
+		return 12
+	case "responsiveness":
+		return cfg.Responsiveness.DurationSec + 5
+	case "bandwidth":
+		id := c.ID()
+		if strings.Contains(id, "speedtest") {
+			return 50
+		}
+		if strings.Contains(id, "networkquality") {
+			return 45
+		}
+		if strings.Contains(id, "iperf") {
+			d := cfg.Bandwidth.Iperf.DurationSec
+			if d <= 0 {
+				d = 30
+			}
+			return d + 15
+		}
+		return 0
+	default:
+		return 5
+	}
+}
@@ -4,10 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"netcheck/internal/config"
+	"netcheck/internal/events"
 	"netcheck/internal/execx"
+	"netcheck/internal/model"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -16,22 +26,32 @@ func fakeExecutor() *execx.FakeExecutor {
 	return &execx.FakeExecutor{
 		Paths: map[string]bool{"netstat": true, "ping": true, "dig": true, "curl": true, "mtr": true, "speedtest-cli": true, "iperf3": true},
 		Outputs: map[string]execx.Result{
-			"netstat -rn":         {Stdout: "default 10.0.0.1"},
-			"ping -c 10 10.0.0.1": {Stdout: "10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 1.0/2.0/3.0/1.0 ms"},
-			"ping -c 10 1.1.1.1":  {Stdout: "10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 1.0/2.0/3.0/1.0 ms"},
-			"ping -c 10 8.8.8.8":  {Stdout: "10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 1.0/2.0/3.0/1.0 ms"},
-			"dig google.com":      {Stdout: ";; Query time: 20 msec"},
-			"curl -w dns:%{time_namelookup} connect:%{time_connect} tls:%{time_appconnect} ttfb:%{time_starttransfer} total:%{time_total} -o /dev/null -s https://example.com": {Stdout: "dns:0.01 connect:0.02 tls:0.03 ttfb:0.04 total:0.05"},
-			"mtr -rwzc 10 1.1.1.1": {Stdout: "HOST: y"},
-			"speedtest-cli --json": {Stdout: `{"download":100000000,"upload":50000000}`},
+			"netstat -rn":            {Stdout: "default 10.0.0.1"},
+			"ping -c 10 -- 10.0.0.1": {Stdout: "10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 1.0/2.0/3.0/1.0 ms"},
+			"ping -c 10 -- 1.1.1.1":  {Stdout: "10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 1.0/2.0/3.0/1.0 ms"},
+			"ping -c 10 -- 8.8.8.8":  {Stdout: "10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 1.0/2.0/3.0/1.0 ms"},
+			"dig -q google.com":      {Stdout: ";; Query time: 20 msec"},
+			"curl -w dns:%{time_namelookup} connect:%{time_connect} tls:%{time_appconnect} ttfb:%{time_starttransfer} total:%{time_total} -o /dev/null -s --url https://example.com": {Stdout: "dns:0.01 connect:0.02 tls:0.03 ttfb:0.04 total:0.05"},
+			"mtr -rwzc 10 -- 1.1.1.1": {Stdout: "HOST: y"},
+			"speedtest-cli --json":    {Stdout: `{"download":100000000,"upload":50000000}`},
 		},
 	}
 }
 
+// testConfig writes a config that keeps every check on the fake executor.
+func testConfig(t *testing.T) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	if err := os.WriteFile(p, []byte("dns:\n  engine: dig\ntls:\n  enabled: false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
 func TestRunCommandJSON(t *testing.T) {
 	var out, errb bytes.Buffer
 	ex := fakeExecutor()
-	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth"}, &out, &errb, ex)
+	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--config", testConfig(t)}, &out, &errb, ex)
 	if code != 0 {
 		t.Fatalf("code=%d err=%s", code, errb.String())
 	}
@@ -47,7 +67,7 @@ func TestRunCommandJSON(t *testing.T) {
 func TestRunCommandJSONL(t *testing.T) {
 	var out, errb bytes.Buffer
 	ex := fakeExecutor()
-	code := runCLI(context.Background(), []string{"run", "--format", "jsonl", "--skip", "bandwidth"}, &out, &errb, ex)
+	code := runCLI(context.Background(), []string{"run", "--format", "jsonl", "--skip", "bandwidth", "--config", testConfig(t)}, &out, &errb, ex)
 	if code != 0 {
 		t.Fatalf("code=%d err=%s", code, errb.String())
 	}
@@ -58,7 +78,7 @@ func TestRunCommandJSONL(t *testing.T) {
 }
 
 func TestManGolden(t *testing.T) {
-	topics := []string{"", "run", "soak", "compare", "config", "exit-codes", "json-schema"}
+	topics := []string{"", "run", "soak", "exporter", "serve-responsiveness", "compare", "explain-score", "config", "exit-codes", "json-schema"}
 	for _, topic := range topics {
 		topic := topic
 		t.Run("topic_"+strings.ReplaceAll(topic, "-", "_"), func(t *testing.T) {
@@ -90,19 +110,235 @@ func TestManGolden(t *testing.T) {
 
 func TestSoakEmitsJSONL(t *testing.T) {
 	var out, errb bytes.Buffer
-	code := runCLI(context.Background(), []string{"soak", "--duration", "1", "--interval", "1", "--skip", "bandwidth"}, &out, &errb, fakeExecutor())
+	code := runCLI(context.Background(), []string{"soak", "--duration", "1", "--interval", "1", "--skip", "bandwidth", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
 	if code != 0 {
 		t.Fatalf("code=%d err=%s", code, errb.String())
 	}
 	if !strings.Contains(out.String(), "run_started") || !strings.Contains(out.String(), "check_result") {
 		t.Fatalf("missing expected events")
 	}
+	var summary struct {
+		Payload struct {
+			Intervals int `json:"intervals"`
+			Checks    []struct {
+				ID              string                     `json:"id"`
+				AvailabilityPct float64                    `json:"availability_pct"`
+				Metrics         map[string]json.RawMessage `json:"metrics"`
+			} `json:"checks"`
+		} `json:"payload"`
+	}
+	sawMetrics := false
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if err := events.Validate([]byte(line)); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		if strings.Contains(line, `"event_type":"check_result"`) && strings.Contains(line, `"id":"local.gateway"`) {
+			sawMetrics = strings.Contains(line, `"avg_ms"`) && strings.Contains(line, `"group":"local"`)
+		}
+		if strings.Contains(line, `"event_type":"run_summary"`) {
+			if err := json.Unmarshal([]byte(line), &summary); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !sawMetrics {
+		t.Fatal("check_result events should carry group and metrics")
+	}
+	if summary.Payload.Intervals < 1 || len(summary.Payload.Checks) == 0 {
+		t.Fatalf("expected aggregate run_summary, got %+v", summary.Payload)
+	}
+	for _, c := range summary.Payload.Checks {
+		if c.ID == "local.gateway" {
+			if c.AvailabilityPct != 100 || c.Metrics["avg_ms"] == nil {
+				t.Fatalf("unexpected gateway aggregate: %+v", c)
+			}
+		}
+	}
+}
+
+func TestSoakReportsGatewayOutage(t *testing.T) {
+	ex := fakeExecutor()
+	ex.Outputs["ping -c 10 -- 10.0.0.1"] = execx.Result{Stdout: "10 packets transmitted, 0 packets received, 100.0% packet loss", ExitCode: 2, Err: errors.New("exit status 2")}
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"soak", "--duration", "1", "--interval", "1", "--skip", "bandwidth", "--config", testConfig(t)}, &out, &errb, ex)
+	if code != 1 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	var started, summary struct {
+		Payload map[string]json.RawMessage `json:"payload"`
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		switch {
+		case strings.Contains(line, `"event_type":"outage_started"`) && started.Payload == nil:
+			_ = json.Unmarshal([]byte(line), &started)
+		case strings.Contains(line, `"event_type":"run_summary"`):
+			_ = json.Unmarshal([]byte(line), &summary)
+		}
+	}
+	if string(started.Payload["cause"]) != `"gateway"` || !strings.Contains(string(started.Payload["affected_groups"]), `"reachability"`) {
+		t.Fatalf("unexpected outage_started: %s", out.String())
+	}
+	var outages struct {
+		Count   int `json:"count"`
+		Outages []struct {
+			Ongoing bool `json:"ongoing"`
+		} `json:"outages"`
+	}
+	if err := json.Unmarshal(summary.Payload["outages"], &outages); err != nil {
+		t.Fatal(err)
+	}
+	if outages.Count != 1 || !outages.Outages[0].Ongoing {
+		t.Fatalf("expected one ongoing outage, got %+v", outages)
+	}
+}
+
+func TestSoakPerGroupSchedules(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	cfg := "dns:\n  engine: dig\ntls:\n  enabled: false\nsoak:\n  schedules:\n    dns: 60\n"
+	if err := os.WriteFile(p, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"soak", "--quiet", "--duration", "2", "--interval", "1", "--select", "reachability,dns", "--config", p}, &out, &errb, fakeExecutor())
+	if code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	var windows []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e struct {
+			EventType string `json:"event_type"`
+			Payload   struct {
+				Groups []string `json:"groups"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.EventType == "interval_summary" {
+			windows = append(windows, strings.Join(e.Payload.Groups, ","))
+		}
+	}
+	if len(windows) < 2 || windows[0] != "dns,reachability" {
+		t.Fatalf("expected several windows starting with every group, got %v", windows)
+	}
+	for _, w := range windows[1:] {
+		if w != "reachability" {
+			t.Fatalf("dns should only run in the first window, got %v", windows)
+		}
+	}
+}
+
+func TestSoakDurationBoundsLongGroupSchedule(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	cfg := "dns:\n  engine: dig\nsoak:\n  schedules:\n    dns: 3600\n"
+	if err := os.WriteFile(p, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	start := time.Now()
+	code := runCLI(context.Background(), []string{"soak", "--quiet", "--duration", "1", "--timeout", "30", "--select", "dns", "--config", p}, &out, &errb, fakeExecutor())
+	if code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("soak slept past --duration waiting for the dns schedule; elapsed=%s", elapsed)
+	}
+	if n := strings.Count(out.String(), `"interval_summary"`); n != 1 {
+		t.Fatalf("expected one window, got %d", n)
+	}
+}
+
+func TestRunRecordAndReplay(t *testing.T) {
+	d := t.TempDir()
+	cassette := filepath.Join(d, "cassette.json")
+	cfg := testConfig(t)
+	statuses := func(out []byte) map[string]model.Status {
+		var r model.Report
+		if err := json.Unmarshal(out, &r); err != nil {
+			t.Fatal(err)
+		}
+		m := map[string]model.Status{}
+		for _, c := range r.Checks {
+			m[c.ID] = c.Status
+		}
+		return m
+	}
+	var out, errb bytes.Buffer
+	if code := runCLI(context.Background(), []string{"run", "--quiet", "--format", "json", "--skip", "bandwidth", "--record", cassette, "--config", cfg}, &out, &errb, fakeExecutor()); code != 0 {
+		t.Fatalf("record: code=%d err=%s", code, errb.String())
+	}
+	recorded := statuses(out.Bytes())
+	out.Reset()
+	// The replay executor must not touch the one passed in.
+	empty := &execx.FakeExecutor{}
+	if code := runCLI(context.Background(), []string{"run", "--quiet", "--format", "json", "--skip", "bandwidth", "--replay", cassette, "--config", cfg}, &out, &errb, empty); code != 0 {
+		t.Fatalf("replay: code=%d err=%s", code, errb.String())
+	}
+	if len(empty.Calls) != 0 {
+		t.Fatalf("replay ran commands: %v", empty.Calls)
+	}
+	replayed := statuses(out.Bytes())
+	if len(replayed) == 0 || len(replayed) != len(recorded) {
+		t.Fatalf("replay differs: recorded=%v replayed=%v", recorded, replayed)
+	}
+	for id, st := range recorded {
+		if replayed[id] != st {
+			t.Fatalf("%s: recorded %s, replayed %s", id, st, replayed[id])
+		}
+	}
+	if code := runCLI(context.Background(), []string{"run", "--record", cassette, "--replay", cassette, "--config", cfg}, &out, &errb, empty); code != 2 {
+		t.Fatalf("expected config error when combining --record and --replay, got %d", code)
+	}
+}
+
+func TestReplayMakesNoLiveNetworkCalls(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+	d := t.TempDir()
+	cassette := filepath.Join(d, "cassette.json")
+	var out, errb bytes.Buffer
+	if code := runCLI(context.Background(), []string{"run", "--quiet", "--select", "local", "--record", cassette, "--config", testConfig(t)}, &out, &errb, fakeExecutor()); code != 0 {
+		t.Fatalf("record: code=%d err=%s", code, errb.String())
+	}
+	// Default engines otherwise: native DNS, TLS checks on, plus a native
+	// HTTP URL and a responsiveness server, all of which work in-process.
+	p := filepath.Join(d, "netcheck.yaml")
+	content := "targets:\n  http_urls: [\"" + srv.URL + "\"]\nhttp:\n  native_urls: [\"" + srv.URL + "\"]\nresponsiveness:\n  url: \"" + srv.URL + "/config\"\n"
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	code := runCLI(context.Background(), []string{"run", "--quiet", "--format", "json", "--skip", "bandwidth", "--replay", cassette, "--config", p}, &out, &errb, &execx.FakeExecutor{})
+	if code != 0 && code != 1 {
+		t.Fatalf("replay: code=%d err=%s", code, errb.String())
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("replay sent %d live requests", n)
+	}
+	var r model.Report
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"dns": "dig not found", "http": "curl not found", "tls": "not recorded in cassette", "responsiveness": "not recorded in cassette"}
+	seen := map[string]bool{}
+	for _, c := range r.Checks {
+		if w, ok := want[c.Group]; ok {
+			seen[c.Group] = true
+			if c.Status != model.StatusSkip || c.Error != w {
+				t.Fatalf("%s: expected skip (%s), got %s (%s)", c.ID, w, c.Status, c.Error)
+			}
+		}
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("expected dns, http, tls and responsiveness checks, got %+v", r.Checks)
+	}
 }
 
 func TestSoakGlobalTimeout(t *testing.T) {
 	var out, errb bytes.Buffer
 	start := time.Now()
-	code := runCLI(context.Background(), []string{"soak", "--duration", "10", "--interval", "1", "--skip", "bandwidth", "--timeout", "1"}, &out, &errb, fakeExecutor())
+	code := runCLI(context.Background(), []string{"soak", "--duration", "10", "--interval", "1", "--skip", "bandwidth", "--timeout", "1", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
 	if code != 0 && code != 1 {
 		t.Fatalf("unexpected code=%d err=%s", code, errb.String())
 	}
@@ -129,6 +365,10 @@ bandwidth:
     enabled: false
   iperf:
     enabled: false
+dns:
+  engine: dig
+tls:
+  enabled: false
 `
 	_ = os.WriteFile(cfgPath, []byte(cfg), 0o644)
 	var out, errb bytes.Buffer
@@ -180,7 +420,7 @@ func TestCompareJSONOutError(t *testing.T) {
 
 func TestRunInvalidFormatExitCode(t *testing.T) {
 	var out, errb bytes.Buffer
-	code := runCLI(context.Background(), []string{"run", "--format", "nope"}, &out, &errb, fakeExecutor())
+	code := runCLI(context.Background(), []string{"run", "--format", "nope", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
 	if code != 4 {
 		t.Fatalf("expected output error 4, got %d", code)
 	}
@@ -188,7 +428,7 @@ func TestRunInvalidFormatExitCode(t *testing.T) {
 
 func TestRunVerboseWritesProgress(t *testing.T) {
 	var out, errb bytes.Buffer
-	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--verbose"}, &out, &errb, fakeExecutor())
+	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--verbose", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
 	if code != 0 {
 		t.Fatalf("code=%d err=%s", code, errb.String())
 	}
@@ -200,8 +440,8 @@ func TestRunVerboseWritesProgress(t *testing.T) {
 func TestRunStrictWarn(t *testing.T) {
 	var out, errb bytes.Buffer
 	ex := fakeExecutor()
-	ex.Outputs["dig google.com"] = execx.Result{Stdout: ";; Query time: 999 msec"}
-	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--strict-warn"}, &out, &errb, ex)
+	ex.Outputs["dig -q google.com"] = execx.Result{Stdout: ";; Query time: 999 msec"}
+	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--strict-warn", "--config", testConfig(t)}, &out, &errb, ex)
 	if code != 1 {
 		t.Fatalf("expected checks-failed code=1, got %d", code)
 	}
@@ -220,7 +460,7 @@ func TestManUnknownTopic(t *testing.T) {
 
 func TestRunOutputPathError(t *testing.T) {
 	var out, errb bytes.Buffer
-	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--out", "/no/such/dir/report.json"}, &out, &errb, fakeExecutor())
+	code := runCLI(context.Background(), []string{"run", "--format", "json", "--skip", "bandwidth", "--out", "/no/such/dir/report.json", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
 	if code != 4 {
 		t.Fatalf("expected output error, got %d", code)
 	}
@@ -241,3 +481,265 @@ func TestRunTimeoutExemptionForLongChecks(t *testing.T) {
 		t.Fatalf("expected timeout exemption to allow delayed speedtest; elapsed=%s", time.Since(start))
 	}
 }
+
+func TestEstimateRunTimeoutAccountsForParallelPlan(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Targets.Ping = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9", "4.4.4.4", "1.0.0.1", "8.8.4.4"}
+	opts := model.RunOptions{Select: []string{"reachability"}}
+	cfg.Concurrency.Workers = 1
+	serial := estimateRunTimeoutSec(cfg, opts)
+	cfg.Concurrency.Workers = 6
+	parallel := estimateRunTimeoutSec(cfg, opts)
+	if serial != 72 || parallel != 30 {
+		t.Fatalf("unexpected estimates serial=%d parallel=%d", serial, parallel)
+	}
+	cfg.Concurrency.GroupLimits = map[string]int{"reachability": 2}
+	if limited := estimateRunTimeoutSec(cfg, opts); limited != 36 {
+		t.Fatalf("expected group limit to raise estimate to 36, got %d", limited)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	if err := os.WriteFile(p, []byte("targets:\n  ping: [1.1.1.1]\nper_check_timout_sec: 5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"config", "validate", p}, &out, &errb, fakeExecutor())
+	if code != 2 {
+		t.Fatalf("expected config error, got %d", code)
+	}
+	want := p + ":3: unknown key per_check_timout_sec (did you mean per_check_timeout_sec?)\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	out.Reset()
+	code = runCLI(context.Background(), []string{"config", "validate", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
+	if code != 0 || !strings.HasSuffix(out.String(), ": ok\n") {
+		t.Fatalf("expected clean config, code=%d out=%q", code, out.String())
+	}
+	out.Reset()
+	code = runCLI(context.Background(), []string{"config", "validate", testConfig(t), "--format", "json"}, &out, &errb, fakeExecutor())
+	if code != 0 || !strings.Contains(out.String(), `"valid": true`) {
+		t.Fatalf("expected flags after the path to apply, code=%d out=%q err=%q", code, out.String(), errb.String())
+	}
+}
+
+func TestExporterServesMetrics(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	var out, errb bytes.Buffer
+	done := make(chan int, 1)
+	go func() {
+		done <- runCLI(context.Background(), []string{"exporter", "--listen", addr, "--interval", "1", "--duration", "3", "--skip", "bandwidth", "--labels", "site=lab", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
+	}()
+	var body string
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(body, "netcheck_score") {
+		time.Sleep(100 * time.Millisecond)
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			continue
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		body = string(b)
+	}
+	if code := <-done; code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	for _, want := range []string{`netcheck_score{site="lab"}`, `netcheck_local_avg_seconds{id="local.gateway",group="local",target="10.0.0.1",site="lab"} 0.002`, `netcheck_check_results_total{id="local.gateway"`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestExporterFollowsGroupSchedules(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	if err := os.WriteFile(p, []byte("dns:\n  engine: dig\nsoak:\n  schedules:\n    dns: 60\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ex := fakeExecutor()
+	var errb bytes.Buffer
+	if code := runCLI(context.Background(), []string{"exporter", "--listen", addr, "--interval", "1", "--duration", "3", "--select", "reachability,dns", "--config", p}, io.Discard, &errb, ex); code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	runs := map[string]int{}
+	for _, c := range ex.Calls {
+		runs[c]++
+	}
+	repeated := false
+	for call, n := range runs {
+		if strings.HasPrefix(call, "dig ") && n != 1 {
+			t.Fatalf("dns should only run in the first window, %q ran %d times", call, n)
+		}
+		if strings.HasPrefix(call, "ping ") && n > 1 {
+			repeated = true
+		}
+	}
+	if !repeated {
+		t.Fatalf("reachability should run every interval, calls: %v", ex.Calls)
+	}
+}
+
+func TestExporterListenError(t *testing.T) {
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"exporter", "--listen", "256.0.0.1:1", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
+	if code != 3 {
+		t.Fatalf("expected runtime error, got %d", code)
+	}
+}
+
+func TestRunPromTextfile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "netcheck.prom")
+	var stdout, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"run", "--format", "prom", "--out", out, "--skip", "bandwidth", "--quiet", "--config", testConfig(t)}, &stdout, &errb, fakeExecutor())
+	if code != 0 && code != 1 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# TYPE netcheck_last_run_timestamp_seconds gauge", "netcheck_score ", `netcheck_check_status{id="local.gateway",group="local",target="10.0.0.1",status="pass"} 1`} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("missing %q in:\n%s", want, b)
+		}
+	}
+}
+
+func TestRunJUnitFormat(t *testing.T) {
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"run", "--format", "junit", "--skip", "bandwidth", "--quiet", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
+	if code != 0 && code != 1 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	var suites struct {
+		Tests  int `xml:"tests,attr"`
+		Suites []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(out.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid junit xml: %v\n%s", err, out.String())
+	}
+	if suites.Tests == 0 || len(suites.Suites) == 0 || suites.Suites[0].Name != "bufferbloat" {
+		t.Fatalf("unexpected suites: %+v", suites)
+	}
+}
+
+func TestCompareMaxRegressionGate(t *testing.T) {
+	d := t.TempDir()
+	p1 := filepath.Join(d, "b.json")
+	p2 := filepath.Join(d, "c.json")
+	_ = os.WriteFile(p1, []byte(`{"score":90,"checks":[{"id":"dns.a","status":"pass","metrics":{"query_ms":20}}]}`), 0o644)
+	_ = os.WriteFile(p2, []byte(`{"score":90,"checks":[{"id":"dns.a","status":"pass","metrics":{"query_ms":30}}]}`), 0o644)
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"compare", "--max-regression", "query_ms=20", p1, p2}, &out, &errb, fakeExecutor())
+	if code != 1 {
+		t.Fatalf("expected checks-failed exit, got %d err=%s", code, errb.String())
+	}
+	if !strings.Contains(errb.String(), "regression: dns.a query_ms regressed 50.0%") || !strings.Contains(out.String(), "REGRESSION") {
+		t.Fatalf("missing regression report:\nstdout=%s\nstderr=%s", out.String(), errb.String())
+	}
+	out.Reset()
+	code = runCLI(context.Background(), []string{"compare", "--max-regression", "query_ms=60", "--out", filepath.Join(d, "diff.txt"), p1, p2}, &out, &errb, fakeExecutor())
+	if code != 0 {
+		t.Fatalf("expected pass within limit, got %d", code)
+	}
+	if code := runCLI(context.Background(), []string{"compare", "--max-regression", "query_ms", p1, p2}, &out, &errb, fakeExecutor()); code != 2 {
+		t.Fatalf("expected config error for bad rule, got %d", code)
+	}
+}
+
+func TestExplainScore(t *testing.T) {
+	d := t.TempDir()
+	p := filepath.Join(d, "report.json")
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"run", "--format", "json", "--out", p, "--skip", "bandwidth", "--config", testConfig(t)}, &out, &errb, fakeExecutor())
+	if code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	out.Reset()
+	if code := runCLI(context.Background(), []string{"explain-score", "--format", "json", p}, &out, &errb, fakeExecutor()); code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	var b model.ScoreBreakdown
+	if err := json.Unmarshal(out.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Score == 0 || len(b.Categories) == 0 {
+		t.Fatalf("unexpected breakdown: %+v", b)
+	}
+
+	legacy := filepath.Join(d, "legacy.json")
+	_ = os.WriteFile(legacy, []byte(`{"score":70,"config":{"thresholds":{"rtt_p95_pass_max_ms":40}},"checks":[
+{"id":"reachability.1.1.1.1","group":"reachability","status":"pass"},
+{"id":"reachability.8.8.8.8","group":"reachability","status":"warn","metrics":{"rtt_p95_ms":62}}]}`), 0o644)
+	out.Reset()
+	if code := runCLI(context.Background(), []string{"explain-score", legacy}, &out, &errb, fakeExecutor()); code != 0 {
+		t.Fatalf("code=%d err=%s", code, errb.String())
+	}
+	if !strings.Contains(out.String(), "lost 20 points: reachability.8.8.8.8 warn (rtt_p95_ms 62 > 40)") {
+		t.Fatalf("unexpected explanation:\n%s", out.String())
+	}
+	if code := runCLI(context.Background(), []string{"explain-score", filepath.Join(d, "missing.json")}, &out, &errb, fakeExecutor()); code != 3 {
+		t.Fatalf("expected runtime error for missing report, got %d", code)
+	}
+}
+
+func TestServeResponsivenessBacksResponsivenessCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	var serveErr bytes.Buffer
+	done := make(chan int, 1)
+	go func() {
+		done <- runCLI(context.Background(), []string{"serve-responsiveness", "--listen", addr, "--duration", "4"}, io.Discard, &serveErr, fakeExecutor())
+	}()
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	content := "responsiveness:\n  url: http://" + addr + "/config\n  duration_sec: 1\n  connections: 2\nthresholds:\n  rpm_pass_min: 1\n  rpm_warn_min: 1\n"
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp, err := http.Get("http://" + addr + "/small"); err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	var out, errb bytes.Buffer
+	code := runCLI(context.Background(), []string{"run", "--format", "json", "--select", "responsiveness", "--quiet", "--config", p}, &out, &errb, fakeExecutor())
+	if code != 0 {
+		t.Fatalf("code=%d err=%s out=%s", code, errb.String(), out.String())
+	}
+	var r model.Report
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Checks) != 1 || r.Checks[0].ID != "responsiveness."+addr || r.Checks[0].Metrics["http_proto"] != "HTTP/2.0" {
+		t.Fatalf("unexpected checks: %+v", r.Checks)
+	}
+	if rpm, _ := r.Checks[0].Metrics["rpm"].(float64); rpm <= 0 {
+		t.Fatalf("expected rpm, got %+v", r.Checks[0].Metrics)
+	}
+	if code := <-done; code != 0 {
+		t.Fatalf("serve code=%d err=%s", code, serveErr.String())
+	}
+}
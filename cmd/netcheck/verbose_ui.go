@@ -305,6 +305,8 @@ func groupColor(group string) string {
 		return "\x1b[38;5;33m"
 	case "http":
 		return "\x1b[38;5;75m"
+	case "tls":
+		return "\x1b[38;5;141m"
 	case "path":
 		return "\x1b[38;5;208m"
 	case "reachability":
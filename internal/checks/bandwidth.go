@@ -3,6 +3,9 @@ package checks
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net"
 	"netcheck/internal/config"
 	"netcheck/internal/eval"
@@ -17,123 +20,480 @@ type SpeedtestCheck struct{}
 
 type IperfCheck struct{}
 
-func (SpeedtestCheck) ID() string    { return "bandwidth.speedtest" }
-func (SpeedtestCheck) Group() string { return "bandwidth" }
-func (IperfCheck) ID() string        { return "bandwidth.iperf" }
-func (IperfCheck) Group() string     { return "bandwidth" }
+func (SpeedtestCheck) ID() string          { return "bandwidth.speedtest" }
+func (SpeedtestCheck) Group() string       { return "bandwidth" }
+func (IperfCheck) ID() string              { return "bandwidth.iperf" }
+func (IperfCheck) Group() string           { return "bandwidth" }
+func (SpeedtestCheck) DependsOn() []string { return gatewayDependency }
+func (IperfCheck) DependsOn() []string     { return gatewayDependency }
 
 func (SpeedtestCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
 	if !cfg.Bandwidth.Speedtest.Enabled {
 		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusSkip, Error: "speedtest disabled"}
 	}
-	if _, err := ex.LookPath("speedtest-cli"); err != nil {
-		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusSkip, Error: "speedtest-cli not found"}
-	}
-	args := []string{"--json"}
-	if cfg.Bandwidth.Speedtest.ServerID != "" {
-		args = append(args, "--server", cfg.Bandwidth.Speedtest.ServerID)
+	p, err := selectSpeedtestProvider(ex, cfg.Bandwidth.Speedtest.Provider)
+	if err != nil {
+		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusSkip, Error: err.Error()}
 	}
 	localTimeout := timeoutSec
 	if localTimeout < 45 {
 		localTimeout = 45
 	}
-	res := runWithTimeout(ctx, localTimeout, ex, "speedtest-cli", args...)
+	res := runWithTimeout(ctx, localTimeout, ex, p.Binary(), p.Args(cfg.Bandwidth.Speedtest.ServerID)...)
 	if isInterruptedError(res.Err) {
 		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
 	if res.Err != nil && res.Stdout == "" {
 		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusFail, Error: res.Err.Error(), DurationMS: time.Since(start).Milliseconds()}
 	}
-	var obj map[string]any
-	if err := json.Unmarshal([]byte(res.Stdout), &obj); err != nil {
-		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusWarn, Error: "unable to parse speedtest json", Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	st, err := p.Parse(res.Stdout)
+	if err != nil {
+		return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusWarn, Error: "unable to parse " + p.Binary() + " json", Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
-	dl := toMbps(obj["download"])
-	ul := toMbps(obj["upload"])
-	status := model.StatusPass
-	errMsg := ""
-	metrics := map[string]any{"download_mbps": dl, "upload_mbps": ul}
-	if cfg.ExpectedPlan.DownloadMbps > 0 {
-		dlPct := dl / cfg.ExpectedPlan.DownloadMbps * 100
-		metrics["download_pct_of_expected"] = dlPct
-		status = eval.UpperIsBetter(dlPct, cfg.Thresholds.ThroughputPassPct, cfg.Thresholds.ThroughputWarnPct)
+	metrics := st.metrics()
+	metrics["provider"] = p.Name()
+	status, errMsg := planStatus(cfg, &st.DownloadMbps, &st.UploadMbps, metrics)
+	return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: status, Metrics: metrics, Error: errMsg, Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+}
+
+// speedtestProvider runs one internet speed test tool and decodes its JSON.
+type speedtestProvider interface {
+	Name() string
+	Binary() string
+	Args(serverID string) []string
+	// DirectionArgs limits a run to "download" or "upload"; nil when the tool
+	// always measures both.
+	DirectionArgs(direction string) []string
+	Parse(out string) (speedtestResult, error)
+}
+
+// speedtestResult is a provider's output in netcheck units. Latency fields
+// are nil when the tool does not report them.
+type speedtestResult struct {
+	DownloadMbps float64
+	UploadMbps   float64
+	PingMs       *float64
+	JitterMs     *float64
+	LossPct      *float64
+	Server       string
+	ISP          string
+}
+
+func (r speedtestResult) metrics() map[string]any {
+	m := map[string]any{"download_mbps": r.DownloadMbps, "upload_mbps": r.UploadMbps}
+	for key, v := range map[string]*float64{"ping_ms": r.PingMs, "jitter_ms": r.JitterMs, "loss_pct": r.LossPct} {
+		if v != nil {
+			m[key] = *v
+		}
 	}
-	if status == model.StatusPass && cfg.ExpectedPlan.UploadMbps > 0 {
-		ulPct := ul / cfg.ExpectedPlan.UploadMbps * 100
-		metrics["upload_pct_of_expected"] = ulPct
-		status = eval.UpperIsBetter(ulPct, cfg.Thresholds.ThroughputPassPct, cfg.Thresholds.ThroughputWarnPct)
+	if r.Server != "" {
+		m["server"] = r.Server
 	}
-	if status != model.StatusPass && (cfg.ExpectedPlan.DownloadMbps > 0 || cfg.ExpectedPlan.UploadMbps > 0) {
-		errMsg = "throughput below expected plan thresholds"
+	if r.ISP != "" {
+		m["isp"] = r.ISP
 	}
-	return model.CheckResult{ID: "bandwidth.speedtest", Group: "bandwidth", Status: status, Metrics: metrics, Error: errMsg, Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	return m
+}
+
+// speedtestProviders in auto-detection order. speedtest-cli comes first
+// because pip installs the Python client under both names.
+var speedtestProviders = []speedtestProvider{speedtestCLI{}, ooklaSpeedtest{}, librespeedCLI{}}
+
+func selectSpeedtestProvider(ex execx.Executor, name string) (speedtestProvider, error) {
+	if name != "" && name != "auto" {
+		for _, p := range speedtestProviders {
+			if p.Name() != name {
+				continue
+			}
+			if _, err := ex.LookPath(p.Binary()); err != nil {
+				return nil, fmt.Errorf("%s not found", p.Binary())
+			}
+			return p, nil
+		}
+		return nil, fmt.Errorf("unknown speedtest provider %q", name)
+	}
+	var bins []string
+	for _, p := range speedtestProviders {
+		if _, err := ex.LookPath(p.Binary()); err == nil {
+			return p, nil
+		}
+		bins = append(bins, p.Binary())
+	}
+	return nil, fmt.Errorf("no speedtest provider found (%s)", strings.Join(bins, ", "))
+}
+
+// speedtestCLI is the Python speedtest-cli: bits per second at the top level.
+type speedtestCLI struct{}
+
+func (speedtestCLI) Name() string   { return "speedtest-cli" }
+func (speedtestCLI) Binary() string { return "speedtest-cli" }
+
+func (speedtestCLI) Args(serverID string) []string {
+	if serverID != "" {
+		return []string{"--json", "--server", serverID}
+	}
+	return []string{"--json"}
+}
+
+func (speedtestCLI) DirectionArgs(direction string) []string {
+	if direction == "download" {
+		return []string{"--no-upload"}
+	}
+	return []string{"--no-download"}
+}
+
+func (speedtestCLI) Parse(out string) (speedtestResult, error) {
+	var v struct {
+		Download float64  `json:"download"`
+		Upload   float64  `json:"upload"`
+		Ping     *float64 `json:"ping"`
+		Server   struct {
+			Name    string `json:"name"`
+			Sponsor string `json:"sponsor"`
+		} `json:"server"`
+		Client struct {
+			ISP string `json:"isp"`
+		} `json:"client"`
+	}
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		return speedtestResult{}, err
+	}
+	return speedtestResult{
+		DownloadMbps: toMbps(v.Download),
+		UploadMbps:   toMbps(v.Upload),
+		PingMs:       v.Ping,
+		Server:       joinNonEmpty(" - ", v.Server.Sponsor, v.Server.Name),
+		ISP:          v.Client.ISP,
+	}, nil
+}
+
+// ooklaSpeedtest is Ookla's speedtest CLI: bytes per second under
+// download.bandwidth and upload.bandwidth.
+type ooklaSpeedtest struct{}
+
+func (ooklaSpeedtest) Name() string   { return "ookla" }
+func (ooklaSpeedtest) Binary() string { return "speedtest" }
+
+func (ooklaSpeedtest) Args(serverID string) []string {
+	args := []string{"--format=json", "--accept-license", "--accept-gdpr"}
+	if serverID != "" {
+		args = append(args, "--server-id="+serverID)
+	}
+	return args
+}
+
+func (ooklaSpeedtest) DirectionArgs(string) []string { return nil }
+
+func (ooklaSpeedtest) Parse(out string) (speedtestResult, error) {
+	var v struct {
+		Ping struct {
+			Jitter  *float64 `json:"jitter"`
+			Latency *float64 `json:"latency"`
+		} `json:"ping"`
+		Download struct {
+			Bandwidth *float64 `json:"bandwidth"`
+		} `json:"download"`
+		Upload struct {
+			Bandwidth float64 `json:"bandwidth"`
+		} `json:"upload"`
+		PacketLoss *float64 `json:"packetLoss"`
+		ISP        string   `json:"isp"`
+		Server     struct {
+			Name     string `json:"name"`
+			Location string `json:"location"`
+		} `json:"server"`
+	}
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		return speedtestResult{}, err
+	}
+	if v.Download.Bandwidth == nil {
+		return speedtestResult{}, errors.New("missing download.bandwidth")
+	}
+	return speedtestResult{
+		DownloadMbps: *v.Download.Bandwidth * 8 / 1_000_000,
+		UploadMbps:   v.Upload.Bandwidth * 8 / 1_000_000,
+		PingMs:       v.Ping.Latency,
+		JitterMs:     v.Ping.Jitter,
+		LossPct:      v.PacketLoss,
+		Server:       joinNonEmpty(" - ", v.Server.Name, v.Server.Location),
+		ISP:          v.ISP,
+	}, nil
+}
+
+// librespeedCLI prints a one-element array (an object in older releases)
+// with download and upload already in Mbps.
+type librespeedCLI struct{}
+
+func (librespeedCLI) Name() string   { return "librespeed" }
+func (librespeedCLI) Binary() string { return "librespeed-cli" }
+
+func (librespeedCLI) Args(serverID string) []string {
+	if serverID != "" {
+		return []string{"--json", "--server", serverID}
+	}
+	return []string{"--json"}
+}
+
+func (librespeedCLI) DirectionArgs(direction string) []string {
+	if direction == "download" {
+		return []string{"--no-upload"}
+	}
+	return []string{"--no-download"}
+}
+
+func (librespeedCLI) Parse(out string) (speedtestResult, error) {
+	type result struct {
+		Download float64  `json:"download"`
+		Upload   float64  `json:"upload"`
+		Ping     *float64 `json:"ping"`
+		Jitter   *float64 `json:"jitter"`
+		Server   struct {
+			Name string `json:"name"`
+		} `json:"server"`
+		Client struct {
+			ISP string `json:"isp"`
+			Org string `json:"org"`
+		} `json:"client"`
+	}
+	var results []result
+	trimmed := strings.TrimSpace(out)
+	if strings.HasPrefix(trimmed, "{") {
+		results = make([]result, 1)
+		if err := json.Unmarshal([]byte(trimmed), &results[0]); err != nil {
+			return speedtestResult{}, err
+		}
+	} else if err := json.Unmarshal([]byte(trimmed), &results); err != nil {
+		return speedtestResult{}, err
+	}
+	if len(results) == 0 {
+		return speedtestResult{}, errors.New("no librespeed results")
+	}
+	r := results[len(results)-1]
+	isp := r.Client.ISP
+	if isp == "" {
+		isp = r.Client.Org
+	}
+	return speedtestResult{
+		DownloadMbps: r.Download,
+		UploadMbps:   r.Upload,
+		PingMs:       r.Ping,
+		JitterMs:     r.Jitter,
+		Server:       r.Server.Name,
+		ISP:          isp,
+	}, nil
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	out := parts[:0:0]
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// planStatus judges each measured direction against expected_plan, recording
+// the percentages it used in metrics. A nil rate was not measured and is not
+// judged; the status is the worse of the two.
+func planStatus(cfg config.Config, dl, ul *float64, metrics map[string]any) (model.Status, string) {
+	status := model.StatusPass
+	judge := func(name string, mbps *float64, plan float64) {
+		if mbps == nil || plan <= 0 {
+			return
+		}
+		pct := *mbps / plan * 100
+		metrics[name+"_pct_of_expected"] = pct
+		if s := eval.UpperIsBetter(pct, cfg.Thresholds.ThroughputPassPct, cfg.Thresholds.ThroughputWarnPct); s == model.StatusFail || status == model.StatusPass {
+			status = s
+		}
+	}
+	judge("download", dl, cfg.ExpectedPlan.DownloadMbps)
+	judge("upload", ul, cfg.ExpectedPlan.UploadMbps)
+	if status != model.StatusPass {
+		return status, "throughput below expected plan thresholds"
+	}
+	return status, ""
 }
 
 func (IperfCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
-	if !cfg.Bandwidth.Iperf.Enabled {
+	ic := cfg.Bandwidth.Iperf
+	if !ic.Enabled {
 		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Status: model.StatusSkip, Error: "iperf disabled"}
 	}
-	if cfg.Bandwidth.Iperf.Target == "" {
+	if ic.Target == "" {
 		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Status: model.StatusSkip, Error: "iperf target not configured"}
 	}
 	if _, err := ex.LookPath("iperf3"); err != nil {
 		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Status: model.StatusSkip, Error: "iperf3 not found"}
 	}
-	args := buildIperfClientArgs(cfg.Bandwidth.Iperf.Target, cfg.Bandwidth.Iperf.ParallelStreams, cfg.Bandwidth.Iperf.DurationSec, true)
+	args := append(buildIperfClientArgs(ic.Target, ic.ParallelStreams, ic.DurationSec, true), iperfModeArgs(ic.Direction, ic.Protocol, ic.BitrateMbps, ic.ParallelStreams)...)
 	localTimeout := timeoutSec
-	minIperfTimeout := cfg.Bandwidth.Iperf.DurationSec + 10
+	minIperfTimeout := ic.DurationSec + 10
 	if localTimeout < minIperfTimeout {
 		localTimeout = minIperfTimeout
 	}
 	res := runWithTimeout(ctx, localTimeout, ex, "iperf3", args...)
 	if isInterruptedError(res.Err) {
-		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: cfg.Bandwidth.Iperf.Target, Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
 	if res.Err != nil && res.Stdout == "" {
 		if isIperfUnreachable(res.Err.Error(), res.Stderr) {
-			return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: cfg.Bandwidth.Iperf.Target, Status: model.StatusSkip, Error: "iperf target unreachable", DurationMS: time.Since(start).Milliseconds()}
+			return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusSkip, Error: "iperf target unreachable", DurationMS: time.Since(start).Milliseconds()}
 		}
-		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: cfg.Bandwidth.Iperf.Target, Status: model.StatusFail, Error: res.Err.Error(), DurationMS: time.Since(start).Milliseconds()}
+		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusFail, Error: res.Err.Error(), DurationMS: time.Since(start).Milliseconds()}
 	}
-	mbps := parseIperfMbps(res.Stdout)
-	status := model.StatusPass
-	errMsg := ""
-	metrics := map[string]any{"download_mbps": mbps}
-	if cfg.ExpectedPlan.DownloadMbps > 0 {
-		pct := mbps / cfg.ExpectedPlan.DownloadMbps * 100
-		metrics["download_pct_of_expected"] = pct
-		status = eval.UpperIsBetter(pct, cfg.Thresholds.ThroughputPassPct, cfg.Thresholds.ThroughputWarnPct)
+	var rep iperfReport
+	if err := json.Unmarshal([]byte(res.Stdout), &rep); err != nil {
+		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusWarn, Error: "unable to parse iperf3 json", Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
-	if status != model.StatusPass && cfg.ExpectedPlan.DownloadMbps > 0 {
-		errMsg = "throughput below expected plan thresholds"
+	// With -J, iperf3 reports its own failures in the document's error field.
+	if rep.Error != "" {
+		if isIperfUnreachable(rep.Error, res.Stderr) {
+			return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusSkip, Error: "iperf target unreachable", Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+		}
+		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusFail, Error: "iperf3: " + rep.Error, Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	}
+	dl, ul := rep.flows(ic.Direction)
+	if dl == nil && ul == nil {
+		return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: model.StatusWarn, Error: "no throughput in iperf3 json", Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	}
+	metrics := map[string]any{"direction": ic.Direction, "protocol": ic.Protocol}
+	var dlMbps, ulMbps *float64
+	if dl != nil {
+		dl.addMetrics("download", metrics)
+		dlMbps = &dl.Mbps
+	}
+	if ul != nil {
+		ul.addMetrics("upload", metrics)
+		ulMbps = &ul.Mbps
 	}
-	return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: cfg.Bandwidth.Iperf.Target, Status: status, Metrics: metrics, Error: errMsg, Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	if cpu := rep.End.CPU; cpu != nil {
+		metrics["cpu_host_pct"] = cpu.HostTotal
+		metrics["cpu_remote_pct"] = cpu.RemoteTotal
+	}
+	status, errMsg := planStatus(cfg, dlMbps, ulMbps, metrics)
+	return model.CheckResult{ID: "bandwidth.iperf", Group: "bandwidth", Target: ic.Target, Status: status, Metrics: metrics, Error: errMsg, Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 }
 
-func toMbps(v any) float64 {
-	f, ok := v.(float64)
-	if !ok {
-		return 0
+// iperfModeArgs follows the base client args, so the default send/tcp run
+// keeps the plain "-c host -P n -t d -J" command line. iperf3 applies -b to
+// each stream, so the configured total is split across them.
+func iperfModeArgs(direction, protocol string, bitrateMbps float64, streams int) []string {
+	var args []string
+	switch direction {
+	case "reverse":
+		args = append(args, "-R")
+	case "bidir":
+		args = append(args, "--bidir")
 	}
-	return f / 1_000_000
+	if protocol == "udp" {
+		args = append(args, "-u")
+	}
+	if bitrateMbps > 0 {
+		perStream := math.Round(bitrateMbps/float64(max(streams, 1))*1000) / 1000
+		args = append(args, "-b", strconv.FormatFloat(perStream, 'f', -1, 64)+"M")
+	}
+	return args
 }
 
-func parseIperfMbps(raw string) float64 {
-	marker := `"bits_per_second":`
-	idx := strings.LastIndex(raw, marker)
-	if idx < 0 {
-		return 0
+// iperfReport is the part of iperf3 -J output netcheck reads. TCP runs fill
+// sum_sent/sum_received; UDP runs also fill sum, whose jitter and loss come
+// from the receiver. --bidir adds the server-to-client flow as *_bidir_reverse.
+type iperfReport struct {
+	Error string `json:"error"`
+	End   struct {
+		Sum                     *iperfSum `json:"sum"`
+		SumSent                 *iperfSum `json:"sum_sent"`
+		SumReceived             *iperfSum `json:"sum_received"`
+		SumBidirReverse         *iperfSum `json:"sum_bidir_reverse"`
+		SumSentBidirReverse     *iperfSum `json:"sum_sent_bidir_reverse"`
+		SumReceivedBidirReverse *iperfSum `json:"sum_received_bidir_reverse"`
+		CPU                     *struct {
+			HostTotal   float64 `json:"host_total"`
+			RemoteTotal float64 `json:"remote_total"`
+		} `json:"cpu_utilization_percent"`
+	} `json:"end"`
+}
+
+type iperfSum struct {
+	BitsPerSecond float64  `json:"bits_per_second"`
+	Retransmits   *int     `json:"retransmits"`
+	JitterMs      *float64 `json:"jitter_ms"`
+	LostPercent   *float64 `json:"lost_percent"`
+}
+
+// iperfFlow is one direction of a run. Mbps is what the receiver got.
+type iperfFlow struct {
+	Mbps        float64
+	Retransmits *int
+	JitterMs    *float64
+	LostPercent *float64
+}
+
+// flows maps the report onto download and upload. Without -R the client
+// sends, so the test flow is upload; with -R it is download.
+func (r iperfReport) flows(direction string) (dl, ul *iperfFlow) {
+	main := newIperfFlow(r.End.SumSent, r.End.SumReceived, r.End.Sum)
+	switch direction {
+	case "reverse":
+		return main, nil
+	case "bidir":
+		return newIperfFlow(r.End.SumSentBidirReverse, r.End.SumReceivedBidirReverse, r.End.SumBidirReverse), main
+	}
+	return nil, main
+}
+
+func newIperfFlow(sent, received, udp *iperfSum) *iperfFlow {
+	var f iperfFlow
+	for _, s := range []*iperfSum{udp, sent, received} {
+		if s == nil {
+			continue
+		}
+		if s.BitsPerSecond > 0 {
+			f.Mbps = s.BitsPerSecond / 1_000_000
+		}
+		if s.JitterMs != nil {
+			f.JitterMs, f.LostPercent = s.JitterMs, s.LostPercent
+		}
+	}
+	if sent != nil {
+		f.Retransmits = sent.Retransmits
+	}
+	if f.Mbps == 0 {
+		return nil
 	}
-	rest := raw[idx+len(marker):]
-	rest = strings.TrimSpace(rest)
-	end := strings.IndexAny(rest, ",}\n")
-	if end < 0 {
-		end = len(rest)
+	return &f
+}
+
+func (f iperfFlow) addMetrics(dir string, metrics map[string]any) {
+	metrics[dir+"_mbps"] = f.Mbps
+	if f.Retransmits != nil {
+		metrics[dir+"_retransmits"] = *f.Retransmits
+		n, _ := metrics["retransmits"].(int)
+		metrics["retransmits"] = n + *f.Retransmits
+	}
+	if f.JitterMs != nil {
+		metrics[dir+"_jitter_ms"] = *f.JitterMs
+		if j, ok := metrics["jitter_ms"].(float64); !ok || *f.JitterMs > j {
+			metrics["jitter_ms"] = *f.JitterMs
+		}
+	}
+	if f.LostPercent != nil {
+		metrics[dir+"_lost_percent"] = *f.LostPercent
+		if l, ok := metrics["lost_percent"].(float64); !ok || *f.LostPercent > l {
+			metrics["lost_percent"] = *f.LostPercent
+		}
+	}
+}
+
+func toMbps(v any) float64 {
+	f, ok := v.(float64)
+	if !ok {
+		return 0
 	}
-	val := strings.TrimSpace(rest[:end])
-	f, _ := strconv.ParseFloat(val, 64)
 	return f / 1_000_000
 }
 
@@ -2,41 +2,178 @@ package checks
 
 import (
 	"context"
+	"errors"
 	"netcheck/internal/config"
 	"netcheck/internal/eval"
 	"netcheck/internal/execx"
 	"netcheck/internal/model"
+	"strings"
 	"time"
 )
 
+// Ramp-up is how long a load generator runs before the loaded pings start,
+// so they are taken while the link is saturated. iperf3 sends as soon as it
+// connects; speedtest tools first pick a server and measure idle latency.
+var (
+	iperfRampUp     = 2 * time.Second
+	speedtestRampUp = 6 * time.Second
+)
+
+// bufferbloatLoadSec keeps each iperf3 phase running past the loaded pings.
+const bufferbloatLoadSec = 12
+
 type BufferbloatCheck struct{ Target string }
 
-func (c BufferbloatCheck) ID() string    { return "bufferbloat." + c.Target }
-func (c BufferbloatCheck) Group() string { return "bufferbloat" }
+func (c BufferbloatCheck) ID() string          { return "bufferbloat." + c.Target }
+func (c BufferbloatCheck) Group() string       { return "bufferbloat" }
+func (c BufferbloatCheck) DependsOn() []string { return gatewayDependency }
+
+type loadPhase struct {
+	Name   string
+	Cmd    []string
+	RampUp time.Duration
+}
+
+// loadPhases saturates the downlink and then the uplink, preferring iperf3
+// and falling back to the configured speedtest provider. The error says why
+// neither can generate load.
+func loadPhases(ex execx.Executor, cfg config.Config) ([]loadPhase, error) {
+	var reasons []string
+	if cfg.Bandwidth.Iperf.Enabled && cfg.Bandwidth.Iperf.Target != "" {
+		if _, err := ex.LookPath("iperf3"); err == nil {
+			args := buildIperfClientArgs(cfg.Bandwidth.Iperf.Target, 2, bufferbloatLoadSec, false)
+			return []loadPhase{
+				{Name: "download", Cmd: append(append([]string{"iperf3"}, args...), "-R"), RampUp: iperfRampUp},
+				{Name: "upload", Cmd: append([]string{"iperf3"}, args...), RampUp: iperfRampUp},
+			}, nil
+		}
+		reasons = append(reasons, "iperf3 not found")
+	}
+	if cfg.Bandwidth.Speedtest.Enabled {
+		p, err := selectSpeedtestProvider(ex, cfg.Bandwidth.Speedtest.Provider)
+		switch {
+		case err != nil:
+			reasons = append(reasons, err.Error())
+		case p.DirectionArgs("download") == nil:
+			reasons = append(reasons, p.Binary()+" cannot run download and upload separately")
+		default:
+			args := append([]string{p.Binary()}, p.Args(cfg.Bandwidth.Speedtest.ServerID)...)
+			var phases []loadPhase
+			for _, dir := range []string{"download", "upload"} {
+				cmd := append(append([]string(nil), args...), p.DirectionArgs(dir)...)
+				phases = append(phases, loadPhase{Name: dir, Cmd: cmd, RampUp: speedtestRampUp})
+			}
+			return phases, nil
+		}
+	}
+	if len(reasons) == 0 {
+		return nil, errors.New("no load generator: enable bandwidth.iperf or bandwidth.speedtest")
+	}
+	return nil, errors.New("no load generator: " + strings.Join(reasons, "; "))
+}
 
 func (c BufferbloatCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
 	if _, err := ex.LookPath("ping"); err != nil {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusSkip, Error: "ping not found"}
 	}
-	idle := runWithTimeout(ctx, timeoutSec, ex, "ping", "-c", "10", c.Target)
+	idle := runWithTimeout(ctx, timeoutSec, ex, "ping", pingArgs(c.Target)...)
 	if isInterruptedError(idle.Err) {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Error: idle.Err.Error(), Raw: idle.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
-	_, idleAvg, _, _ := parsePing(idle.Stdout)
-	// Load proxy: run one bandwidth command between baseline and loaded latency samples.
-	if cfg.Bandwidth.Iperf.Enabled && cfg.Bandwidth.Iperf.Target != "" {
-		args := buildIperfClientArgs(cfg.Bandwidth.Iperf.Target, 2, 5, false)
-		_ = runWithTimeout(ctx, timeoutSec, ex, "iperf3", args...)
-	} else if cfg.Bandwidth.Speedtest.Enabled {
-		_ = runWithTimeout(ctx, timeoutSec, ex, "speedtest-cli", "--json")
-	}
-	loaded := runWithTimeout(ctx, timeoutSec, ex, "ping", "-c", "10", c.Target)
-	if isInterruptedError(loaded.Err) {
-		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Error: loaded.Err.Error(), Raw: loaded.Stdout, DurationMS: time.Since(start).Milliseconds()}
-	}
-	_, loadedAvg, _, _ := parsePing(loaded.Stdout)
-	delta := loadedAvg - idleAvg
-	status := eval.LowerIsBetter(delta, cfg.Thresholds.LoadedLatencyPassDeltaMs, cfg.Thresholds.LoadedLatencyWarnDeltaMs)
-	return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: status, Metrics: map[string]any{"idle_ms": idleAvg, "loaded_ms": loadedAvg, "delta_ms": delta}, Raw: loaded.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	idleLoss, idleAvg, _, _ := parsePing(idle.Stdout)
+	idleSamples := latencySamples(idle.Stdout)
+	metrics := map[string]any{"idle_loss_pct": idleLoss}
+	// Without idle replies there is no baseline to grade against, and
+	// loading an unreachable target would only prolong the failure.
+	if len(idleSamples) == 0 {
+		metrics["grade"] = "F"
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Metrics: metrics, Error: "no idle ping replies", Raw: idle.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	}
+	idleP50 := percentile(idleSamples, 50)
+	metrics["idle_ms"] = idleAvg
+	metrics["idle_p50_ms"] = idleP50
+	phases, err := loadPhases(ex, cfg)
+	if err != nil {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusSkip, Metrics: metrics, Error: err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	var errs, raw []string
+	worst, loadedP50 := 0.0, 0.0
+	measured, failed := false, false
+	for _, ph := range phases {
+		loaded, load, ok := c.underLoad(ctx, ex, timeoutSec, ph)
+		if isInterruptedError(loaded.Err) {
+			return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Metrics: metrics, Error: loaded.Err.Error(), Raw: loaded.Stdout, DurationMS: time.Since(start).Milliseconds()}
+		}
+		if !ok {
+			msg := ph.Name + " load ended before latency was sampled"
+			if load.Err != nil {
+				msg += ": " + load.Err.Error()
+			}
+			errs = append(errs, msg)
+			continue
+		}
+		raw = append(raw, loaded.Stdout)
+		loss, _, _, _ := parsePing(loaded.Stdout)
+		metrics[ph.Name+"_loss_pct"] = loss
+		samples := latencySamples(loaded.Stdout)
+		if len(samples) == 0 {
+			// Every reply lost under load is the worst case, not a zero delta.
+			errs = append(errs, "no ping replies under "+ph.Name+" load")
+			failed = true
+			continue
+		}
+		p50 := percentile(samples, 50)
+		delta := p50 - idleP50
+		metrics[ph.Name+"_loaded_p50_ms"] = p50
+		metrics[ph.Name+"_loaded_p95_ms"] = percentile(samples, 95)
+		metrics[ph.Name+"_delta_ms"] = delta
+		if !measured || delta > worst {
+			worst, loadedP50 = delta, p50
+		}
+		measured = true
+	}
+	if !measured && !failed {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusSkip, Metrics: metrics, Error: strings.Join(errs, "; "), DurationMS: time.Since(start).Milliseconds()}
+	}
+	if measured {
+		metrics["loaded_ms"] = loadedP50
+		metrics["delta_ms"] = worst
+	}
+	metrics["grade"] = "F"
+	status := model.StatusFail
+	if !failed {
+		metrics["grade"] = eval.BufferbloatGrade(worst)
+		status = eval.LowerIsBetter(worst, cfg.Thresholds.LoadedLatencyPassDeltaMs, cfg.Thresholds.LoadedLatencyWarnDeltaMs)
+	}
+	return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: status, Metrics: metrics, Error: strings.Join(errs, "; "), Raw: strings.Join(raw, "\n"), DurationMS: time.Since(start).Milliseconds()}
+}
+
+// underLoad starts the phase's load command and, once it has ramped up, pings
+// the target while the load is still running. ok is false when the load
+// ended before ramp-up, so there was nothing to sample under.
+func (c BufferbloatCheck) underLoad(ctx context.Context, ex execx.Executor, timeoutSec int, ph loadPhase) (loaded, res execx.Result, ok bool) {
+	done := make(chan execx.Result, 1)
+	go func() { done <- runWithTimeout(ctx, timeoutSec, ex, ph.Cmd[0], ph.Cmd[1:]...) }()
+	select {
+	case res = <-done:
+		return execx.Result{}, res, false
+	case <-ctx.Done():
+		return execx.Result{Err: ctx.Err()}, <-done, false
+	case <-time.After(ph.RampUp):
+	}
+	loaded = runWithTimeout(ctx, timeoutSec, ex, "ping", pingArgs(c.Target)...)
+	return loaded, <-done, true
+}
+
+// latencySamples returns the per-reply times, or the summary average when
+// ping printed no per-reply lines.
+func latencySamples(out string) []float64 {
+	if s := parsePingTimeSamples(out); len(s) > 0 {
+		return s
+	}
+	if _, avg, _, _ := parsePing(out); avg > 0 {
+		return []float64{avg}
+	}
+	return nil
 }
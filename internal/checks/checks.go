@@ -12,3 +12,20 @@ type Check interface {
 	Group() string
 	Run(ctx context.Context, exec execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult
 }
+
+// Dependent is implemented by checks that are only meaningful when other checks
+// succeed. DependsOn lists prerequisite check IDs, most specific first.
+type Dependent interface {
+	DependsOn() []string
+}
+
+// DependenciesOf returns the prerequisite IDs of c, or nil if it has none.
+func DependenciesOf(c Check) []string {
+	if d, ok := c.(Dependent); ok {
+		return d.DependsOn()
+	}
+	return nil
+}
+
+// gatewayDependency is shared by every check that needs a working local uplink.
+var gatewayDependency = []string{LocalCheck{}.ID()}
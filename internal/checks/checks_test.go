@@ -2,10 +2,19 @@ package checks
 
 import (
 	"context"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"netcheck/internal/config"
+	"netcheck/internal/dnsx/dnsxtest"
 	"netcheck/internal/execx"
 	"netcheck/internal/model"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -26,9 +35,9 @@ func pingOK() string {
 func TestLocalCheckIncludesInterfaceMetrics(t *testing.T) {
 	c := cfg()
 	fx := &execx.FakeExecutor{Paths: map[string]bool{"netstat": true, "ping": true, "ifconfig": true}, Outputs: map[string]execx.Result{
-		"netstat -rn":         {Stdout: "default 10.0.0.1"},
-		"ping -c 10 10.0.0.1": {Stdout: pingOK()},
-		"ifconfig":            {Stdout: "en0:\n\tstatus: active\n\tinet 10.0.0.5\n"},
+		"netstat -rn":            {Stdout: "default 10.0.0.1"},
+		"ping -c 10 -- 10.0.0.1": {Stdout: pingOK()},
+		"ifconfig":               {Stdout: "en0:\n\tstatus: active\n\tinet 10.0.0.5\n"},
 	}}
 	r := LocalCheck{}.Run(context.Background(), fx, c, 2)
 	if r.Status != model.StatusPass {
@@ -41,8 +50,9 @@ func TestLocalCheckIncludesInterfaceMetrics(t *testing.T) {
 
 func TestDNSCheckWithResolver(t *testing.T) {
 	c := cfg()
+	c.DNS.Engine = "dig"
 	fx := &execx.FakeExecutor{Paths: map[string]bool{"dig": true}, Outputs: map[string]execx.Result{
-		"dig @1.1.1.1 google.com": {Stdout: ";; Query time: 12 msec"},
+		"dig @1.1.1.1 -q google.com": {Stdout: ";; Query time: 12 msec"},
 	}}
 	r := DNSCheck{Domain: "google.com", Resolver: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
 	if r.Status != model.StatusPass {
@@ -56,7 +66,7 @@ func TestDNSCheckWithResolver(t *testing.T) {
 func TestPathCheckTracerouteFallback(t *testing.T) {
 	c := cfg()
 	fx := &execx.FakeExecutor{Paths: map[string]bool{"traceroute": true}, Outputs: map[string]execx.Result{
-		"traceroute -m 15 1.1.1.1": {Stdout: "traceroute to 1.1.1.1\n"},
+		"traceroute -m 15 -- 1.1.1.1": {Stdout: "traceroute to 1.1.1.1\n"},
 	}}
 	r := PathCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
 	if r.Status != model.StatusPass {
@@ -69,8 +79,8 @@ func TestPathCheckFallsBackWhenMTRRuntimeFails(t *testing.T) {
 	fx := &execx.FakeExecutor{
 		Paths: map[string]bool{"mtr": true, "traceroute": true},
 		Outputs: map[string]execx.Result{
-			"mtr -rwzc 10 1.1.1.1":     {Err: errors.New("exit status 1"), ExitCode: 1},
-			"traceroute -m 15 1.1.1.1": {Stdout: "traceroute to 1.1.1.1\n 1 a 1.0 ms\n"},
+			"mtr -rwzc 10 -- 1.1.1.1":     {Err: errors.New("exit status 1"), ExitCode: 1},
+			"traceroute -m 15 -- 1.1.1.1": {Stdout: "traceroute to 1.1.1.1\n 1 a 1.0 ms\n"},
 		},
 	}
 	r := PathCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
@@ -91,24 +101,26 @@ func TestIperfUnreachableIsSkip(t *testing.T) {
 }
 
 func TestBufferbloatUsesSpeedtestLoadProxy(t *testing.T) {
+	fastRampUp(t)
 	c := cfg()
 	c.Bandwidth.Iperf.Target = ""
 	fx := &execx.FakeExecutor{Paths: map[string]bool{"ping": true, "speedtest-cli": true}, Outputs: map[string]execx.Result{
-		"ping -c 10 1.1.1.1":   {Stdout: pingOK()},
-		"speedtest-cli --json": {Stdout: `{"download":100000000,"upload":50000000}`},
+		"ping -c 10 -- 1.1.1.1":              {Stdout: pingOK()},
+		"speedtest-cli --json --no-upload":   {Stdout: `{"download":100000000}`},
+		"speedtest-cli --json --no-download": {Stdout: `{"upload":50000000}`},
+	}, Delays: map[string]time.Duration{
+		"speedtest-cli --json --no-upload":   100 * time.Millisecond,
+		"speedtest-cli --json --no-download": 100 * time.Millisecond,
 	}}
 	r := BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
-	if r.Status == "" {
-		t.Fatalf("expected status")
+	if r.Status != model.StatusPass {
+		t.Fatalf("expected pass, got %s err=%s", r.Status, r.Error)
 	}
-	seen := false
-	for _, call := range fx.Calls {
-		if call == "speedtest-cli --json" {
-			seen = true
-		}
+	if _, ok := r.Metrics["download_delta_ms"]; !ok {
+		t.Fatalf("expected download phase metrics, got %+v", r.Metrics)
 	}
-	if !seen {
-		t.Fatalf("expected speedtest load call")
+	if _, ok := r.Metrics["upload_delta_ms"]; !ok {
+		t.Fatalf("expected upload phase metrics, got %+v", r.Metrics)
 	}
 }
 
@@ -117,7 +129,7 @@ func TestReachabilityTimeout(t *testing.T) {
 	fx := &execx.FakeExecutor{
 		Paths: map[string]bool{"ping": true},
 		Delays: map[string]time.Duration{
-			"ping -c 10 1.1.1.1": 2 * time.Second,
+			"ping -c 10 -- 1.1.1.1": 2 * time.Second,
 		},
 	}
 	r := ReachabilityCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 1)
@@ -131,7 +143,7 @@ func TestReachabilityUsesP95Threshold(t *testing.T) {
 	fx := &execx.FakeExecutor{
 		Paths: map[string]bool{"ping": true},
 		Outputs: map[string]execx.Result{
-			"ping -c 10 1.1.1.1": {Stdout: "64 bytes from 1.1.1.1: icmp_seq=0 ttl=57 time=5.0 ms\n64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=6.0 ms\n64 bytes from 1.1.1.1: icmp_seq=2 ttl=57 time=120.0 ms\n10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 5.000/10.000/120.000/1.000 ms"},
+			"ping -c 10 -- 1.1.1.1": {Stdout: "64 bytes from 1.1.1.1: icmp_seq=0 ttl=57 time=5.0 ms\n64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=6.0 ms\n64 bytes from 1.1.1.1: icmp_seq=2 ttl=57 time=120.0 ms\n10 packets transmitted, 10 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 5.000/10.000/120.000/1.000 ms"},
 		},
 	}
 	r := ReachabilityCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
@@ -151,6 +163,126 @@ func TestSpeedtestWarnOnInvalidJSON(t *testing.T) {
 	}
 }
 
+func TestNetworkQualityMapsThroughputAndResponsiveness(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"networkQuality": true}, Outputs: map[string]execx.Result{
+		"networkQuality -c": {Stdout: `{"base_rtt":21.5,"dl_flows":12,"dl_throughput":95000000,"ul_flows":12,"ul_throughput":20000000,"responsiveness":1210.4,"dl_responsiveness":1180,"ul_responsiveness":1450,"interface_name":"en0"}`},
+	}}
+	r := NetworkQualityCheck{}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusFail || r.Error != "throughput below expected plan thresholds" {
+		t.Fatalf("expected upload below plan to fail, got %s err=%s", r.Status, r.Error)
+	}
+	want := map[string]float64{"download_mbps": 95, "upload_mbps": 20, "download_pct_of_expected": 95, "upload_pct_of_expected": 40, "base_rtt_ms": 21.5, "rpm": 1210.4, "download_rpm": 1180, "upload_rpm": 1450}
+	for k, v := range want {
+		if r.Metrics[k] != v {
+			t.Fatalf("metric %s: want %v, got %v (%+v)", k, v, r.Metrics[k], r.Metrics)
+		}
+	}
+}
+
+func TestNetworkQualityOlderOutputAndErrors(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"networkQuality": true}, Outputs: map[string]execx.Result{
+		"networkQuality -c": {Stdout: `{"base_rtt":30,"dl_throughput":120000000,"ul_throughput":60000000,"responsiveness":900}`},
+	}}
+	r := NetworkQualityCheck{}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusPass || r.Metrics["rpm"] != 900.0 {
+		t.Fatalf("unexpected result: %s %+v", r.Status, r.Metrics)
+	}
+	if _, ok := r.Metrics["download_rpm"]; ok {
+		t.Fatalf("per-direction rpm must be absent when not reported: %+v", r.Metrics)
+	}
+	fx.Outputs["networkQuality -c"] = execx.Result{Stdout: `{"base_rtt":30,"dl_throughput":120000000,"responsiveness":900}`}
+	r = NetworkQualityCheck{}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusPass || r.Metrics["download_pct_of_expected"] != 120.0 {
+		t.Fatalf("a missing uplink must not fail the plan check: %s err=%s %+v", r.Status, r.Error, r.Metrics)
+	}
+	for _, k := range []string{"upload_mbps", "upload_pct_of_expected"} {
+		if _, ok := r.Metrics[k]; ok {
+			t.Fatalf("%s must be absent without ul_throughput: %+v", k, r.Metrics)
+		}
+	}
+	fx.Outputs["networkQuality -c"] = execx.Result{Stdout: "Error: no network"}
+	if r := (NetworkQualityCheck{}).Run(context.Background(), fx, c, 2); r.Status != model.StatusWarn {
+		t.Fatalf("expected warn on unparsable output, got %s", r.Status)
+	}
+	if r := (NetworkQualityCheck{}).Run(context.Background(), &execx.FakeExecutor{}, c, 2); r.Status != model.StatusSkip {
+		t.Fatalf("expected skip without networkQuality, got %s", r.Status)
+	}
+}
+
+func TestSpeedtestProvidersExtractMetadata(t *testing.T) {
+	cases := []struct {
+		provider string
+		key      string
+		out      string
+		want     map[string]any
+	}{
+		{"speedtest-cli", "speedtest-cli --json --server 42",
+			`{"download":93000000,"upload":12000000,"ping":18.2,"server":{"name":"Berlin","sponsor":"ExampleNet"},"client":{"isp":"Example ISP"}}`,
+			map[string]any{"download_mbps": 93.0, "upload_mbps": 12.0, "ping_ms": 18.2, "server": "ExampleNet - Berlin", "isp": "Example ISP", "provider": "speedtest-cli"}},
+		{"ookla", "speedtest --format=json --accept-license --accept-gdpr --server-id=42",
+			`{"type":"result","ping":{"jitter":1.5,"latency":11.25},"download":{"bandwidth":12500000,"bytes":1},"upload":{"bandwidth":2500000},"packetLoss":0.5,"isp":"Example ISP","server":{"id":42,"name":"ExampleNet","location":"Berlin"}}`,
+			map[string]any{"download_mbps": 100.0, "upload_mbps": 20.0, "ping_ms": 11.25, "jitter_ms": 1.5, "loss_pct": 0.5, "server": "ExampleNet - Berlin", "isp": "Example ISP", "provider": "ookla"}},
+		{"librespeed", "librespeed-cli --json --server 42",
+			`[{"timestamp":"2024-01-01T00:00:00Z","server":{"name":"Frankfurt","url":"https://lib.example/"},"client":{"ip":"203.0.113.9","org":"AS64500 Example"},"bytes_sent":1,"bytes_received":2,"ping":9.5,"jitter":0.7,"upload":41.2,"download":180.4,"share":""}]`,
+			map[string]any{"download_mbps": 180.4, "upload_mbps": 41.2, "ping_ms": 9.5, "jitter_ms": 0.7, "server": "Frankfurt", "isp": "AS64500 Example", "provider": "librespeed"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider, func(t *testing.T) {
+			c := cfg()
+			c.ExpectedPlan = config.Defaults().ExpectedPlan
+			c.Bandwidth.Speedtest.Provider = tc.provider
+			c.Bandwidth.Speedtest.ServerID = "42"
+			bin := strings.Fields(tc.key)[0]
+			fx := &execx.FakeExecutor{Paths: map[string]bool{bin: true}, Outputs: map[string]execx.Result{tc.key: {Stdout: tc.out}}}
+			r := SpeedtestCheck{}.Run(context.Background(), fx, c, 2)
+			if r.Status != model.StatusPass {
+				t.Fatalf("expected pass, got %s err=%s calls=%v", r.Status, r.Error, fx.Calls)
+			}
+			if len(r.Metrics) != len(tc.want) {
+				t.Fatalf("unexpected metrics %+v", r.Metrics)
+			}
+			for k, v := range tc.want {
+				if r.Metrics[k] != v {
+					t.Fatalf("metric %s: want %v, got %v", k, v, r.Metrics[k])
+				}
+			}
+		})
+	}
+}
+
+func TestSpeedtestProviderAutoDetection(t *testing.T) {
+	c := cfg()
+	for _, tc := range []struct {
+		paths map[string]bool
+		want  string
+	}{
+		{map[string]bool{"speedtest-cli": true, "speedtest": true, "librespeed-cli": true}, "speedtest-cli"},
+		{map[string]bool{"speedtest": true, "librespeed-cli": true}, "ookla"},
+		{map[string]bool{"librespeed-cli": true}, "librespeed"},
+	} {
+		p, err := selectSpeedtestProvider(&execx.FakeExecutor{Paths: tc.paths}, "auto")
+		if err != nil || p.Name() != tc.want {
+			t.Fatalf("paths %v: want %s, got %v err=%v", tc.paths, tc.want, p, err)
+		}
+	}
+	r := SpeedtestCheck{}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusSkip || r.Error != "no speedtest provider found (speedtest-cli, speedtest, librespeed-cli)" {
+		t.Fatalf("expected skip naming every tool, got %s %q", r.Status, r.Error)
+	}
+	c.Bandwidth.Speedtest.Provider = "ookla"
+	r = SpeedtestCheck{}.Run(context.Background(), &execx.FakeExecutor{Paths: map[string]bool{"speedtest-cli": true}}, c, 2)
+	if r.Status != model.StatusSkip || r.Error != "speedtest not found" {
+		t.Fatalf("explicit provider must not fall back, got %s %q", r.Status, r.Error)
+	}
+	for _, p := range speedtestProviders {
+		if !slices.Contains(config.SpeedtestProviders, p.Name()) {
+			t.Fatalf("provider %s is not accepted by config validation", p.Name())
+		}
+	}
+}
+
 func TestIperfMissingTargetSkip(t *testing.T) {
 	c := cfg()
 	c.Bandwidth.Iperf.Target = ""
@@ -172,6 +304,7 @@ func TestPathCheckSkipWhenNoTools(t *testing.T) {
 
 func TestDNSMissingToolSkip(t *testing.T) {
 	c := cfg()
+	c.DNS.Engine = "dig"
 	fx := &execx.FakeExecutor{Paths: map[string]bool{}}
 	r := DNSCheck{Domain: "google.com"}.Run(context.Background(), fx, c, 2)
 	if r.Status != model.StatusSkip {
@@ -202,7 +335,7 @@ func TestLocalGatewayWarnWhenMissing(t *testing.T) {
 func TestPathMTRLossFail(t *testing.T) {
 	c := cfg()
 	fx := &execx.FakeExecutor{Paths: map[string]bool{"mtr": true}, Outputs: map[string]execx.Result{
-		"mtr -rwzc 10 1.1.1.1": {Stdout: "1.|-- hop-a 0.0%\n2.|-- hop-b 5.0%"},
+		"mtr -rwzc 10 -- 1.1.1.1": {Stdout: "1.|-- hop-a 0.0%\n2.|-- hop-b 5.0%"},
 	}}
 	r := PathCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
 	if r.Status != model.StatusFail {
@@ -228,11 +361,93 @@ func TestIperfParsePass(t *testing.T) {
 		"iperf3 -c 10.0.0.2 -P 4 -t 30 -J": {Stdout: `{"end":{"sum_received":{"bits_per_second":160000000}}}`},
 	}}
 	r := IperfCheck{}.Run(context.Background(), fx, c, 2)
-	if r.Status == model.StatusFail || r.Metrics["download_mbps"] == nil {
+	if r.Status == model.StatusFail || r.Metrics["upload_mbps"] == nil {
 		t.Fatalf("expected throughput metric and non-fail status, got %+v", r)
 	}
 }
 
+func TestIperfDirectionsAndUDP(t *testing.T) {
+	cases := []struct {
+		name, direction, protocol string
+		bitrate                   float64
+		streams                   int
+		key, stdout               string
+		status                    model.Status
+		want                      map[string]any
+	}{
+		{
+			name: "send", direction: "send", protocol: "tcp",
+			key:    "iperf3 -c 10.0.0.2 -P 4 -t 30 -J",
+			stdout: `{"end":{"sum_sent":{"bits_per_second":52000000,"retransmits":7},"sum_received":{"bits_per_second":48000000},"cpu_utilization_percent":{"host_total":12.5,"remote_total":3.25}}}`,
+			status: model.StatusPass,
+			want:   map[string]any{"upload_mbps": 48.0, "upload_pct_of_expected": 96.0, "upload_retransmits": 7, "retransmits": 7, "cpu_host_pct": 12.5, "cpu_remote_pct": 3.25},
+		},
+		{
+			name: "reverse", direction: "reverse", protocol: "tcp",
+			key:    "iperf3 -c 10.0.0.2 -P 4 -t 30 -J -R",
+			stdout: `{"end":{"sum_sent":{"bits_per_second":71000000,"retransmits":120},"sum_received":{"bits_per_second":70000000}}}`,
+			status: model.StatusWarn,
+			want:   map[string]any{"download_mbps": 70.0, "download_pct_of_expected": 70.0, "download_retransmits": 120},
+		},
+		{
+			name: "bidir judges each direction", direction: "bidir", protocol: "tcp",
+			key:    "iperf3 -c 10.0.0.2 -P 4 -t 30 -J --bidir",
+			stdout: `{"end":{"sum_sent":{"bits_per_second":20000000,"retransmits":3},"sum_received":{"bits_per_second":20000000},"sum_sent_bidir_reverse":{"bits_per_second":95000000,"retransmits":1},"sum_received_bidir_reverse":{"bits_per_second":95000000}}}`,
+			status: model.StatusFail,
+			want:   map[string]any{"download_mbps": 95.0, "download_pct_of_expected": 95.0, "upload_mbps": 20.0, "upload_pct_of_expected": 40.0, "retransmits": 4},
+		},
+		{
+			name: "udp", direction: "reverse", protocol: "udp", bitrate: 100,
+			key:    "iperf3 -c 10.0.0.2 -P 4 -t 30 -J -R -u -b 25M",
+			stdout: `{"end":{"sum":{"bits_per_second":99000000,"jitter_ms":0.42,"lost_packets":50,"packets":10000,"lost_percent":0.5}}}`,
+			status: model.StatusPass,
+			want:   map[string]any{"download_mbps": 99.0, "download_jitter_ms": 0.42, "jitter_ms": 0.42, "lost_percent": 0.5},
+		},
+		{
+			name: "udp bitrate split across streams", direction: "send", protocol: "udp", bitrate: 100, streams: 3,
+			key:    "iperf3 -c 10.0.0.2 -P 3 -t 30 -J -u -b 33.333M",
+			stdout: `{"end":{"sum":{"bits_per_second":60000000,"jitter_ms":0.3,"lost_packets":0,"packets":5000,"lost_percent":0}}}`,
+			status: model.StatusPass,
+			want:   map[string]any{"upload_mbps": 60.0, "upload_pct_of_expected": 120.0},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := cfg()
+			c.Bandwidth.Iperf.Direction = tc.direction
+			c.Bandwidth.Iperf.Protocol = tc.protocol
+			c.Bandwidth.Iperf.BitrateMbps = tc.bitrate
+			if tc.streams > 0 {
+				c.Bandwidth.Iperf.ParallelStreams = tc.streams
+			}
+			fx := &execx.FakeExecutor{Paths: map[string]bool{"iperf3": true}, Outputs: map[string]execx.Result{tc.key: {Stdout: tc.stdout}}}
+			r := IperfCheck{}.Run(context.Background(), fx, c, 2)
+			if r.Status != tc.status {
+				t.Fatalf("expected %s, got %s (err=%s)", tc.status, r.Status, r.Error)
+			}
+			for k, v := range tc.want {
+				if r.Metrics[k] != v {
+					t.Fatalf("metric %s: expected %v, got %v (all: %v)", k, v, r.Metrics[k], r.Metrics)
+				}
+			}
+		})
+	}
+}
+
+func TestIperfJSONErrorIsReported(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"iperf3": true}, Outputs: map[string]execx.Result{
+		"iperf3 -c 10.0.0.2 -P 4 -t 30 -J": {Stdout: `{"start":{},"intervals":[],"end":{},"error":"unable to connect to server: Connection refused"}`, Err: errors.New("exit status 1"), ExitCode: 1},
+	}}
+	if r := (IperfCheck{}).Run(context.Background(), fx, c, 2); r.Status != model.StatusSkip || r.Error != "iperf target unreachable" {
+		t.Fatalf("expected unreachable skip, got %s (%s)", r.Status, r.Error)
+	}
+	fx.Outputs["iperf3 -c 10.0.0.2 -P 4 -t 30 -J"] = execx.Result{Stdout: `{"end":{},"error":"the server is busy running a test. try again later"}`, Err: errors.New("exit status 1"), ExitCode: 1}
+	if r := (IperfCheck{}).Run(context.Background(), fx, c, 2); r.Status != model.StatusFail || r.Error != "iperf3: the server is busy running a test. try again later" {
+		t.Fatalf("expected iperf3 error to fail the check, got %s (%s)", r.Status, r.Error)
+	}
+}
+
 func TestIperfTargetWithPortUsesPortFlag(t *testing.T) {
 	c := cfg()
 	c.Bandwidth.Iperf.Target = "10.0.0.2:5201"
@@ -249,11 +464,459 @@ func TestBufferbloatIperfTargetWithPort(t *testing.T) {
 	c := cfg()
 	c.Bandwidth.Iperf.Target = "10.0.0.2:5201"
 	fx := &execx.FakeExecutor{Paths: map[string]bool{"ping": true, "iperf3": true}, Outputs: map[string]execx.Result{
-		"ping -c 10 1.1.1.1":                   {Stdout: pingOK()},
-		"iperf3 -c 10.0.0.2 -p 5201 -P 2 -t 5": {Stdout: "ok"},
+		"ping -c 10 -- 1.1.1.1":                    {Stdout: pingOK()},
+		"iperf3 -c 10.0.0.2 -p 5201 -P 2 -t 12 -R": {Stdout: "ok"},
+		"iperf3 -c 10.0.0.2 -p 5201 -P 2 -t 12":    {Stdout: "ok"},
 	}}
 	r := BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
 	if r.Status == "" {
 		t.Fatalf("expected status")
 	}
 }
+
+func TestBufferbloatPingsWhileLoadIsRunning(t *testing.T) {
+	fastRampUp(t)
+	c := cfg()
+	download := "iperf3 -c 10.0.0.2 -P 2 -t 12 -R"
+	upload := "iperf3 -c 10.0.0.2 -P 2 -t 12"
+	fx := &execx.FakeExecutor{
+		Paths: map[string]bool{"ping": true, "iperf3": true},
+		Outputs: map[string]execx.Result{
+			download: {Stdout: "ok"},
+			upload:   {Stdout: "ok"},
+		},
+		Sequences: map[string][]execx.Result{
+			"ping -c 10 -- 1.1.1.1": {
+				{Stdout: pingTimes(10, 11, 12)},
+				{Stdout: pingTimes(70, 81, 90, 150)},
+				{Stdout: pingTimes(30, 41, 50)},
+			},
+		},
+		Delays: map[string]time.Duration{download: 150 * time.Millisecond, upload: 150 * time.Millisecond},
+	}
+	r := BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	want := "ping -c 10 -- 1.1.1.1," + download + ",ping -c 10 -- 1.1.1.1," + upload + ",ping -c 10 -- 1.1.1.1"
+	if got := strings.Join(fx.Calls, ","); got != want {
+		t.Fatalf("loaded pings must start after their load, got %s", got)
+	}
+	if r.Metrics["download_loaded_p50_ms"] != 81.0 || r.Metrics["download_delta_ms"] != 70.0 || r.Metrics["upload_delta_ms"] != 30.0 {
+		t.Fatalf("unexpected phase metrics: %+v", r.Metrics)
+	}
+	if r.Metrics["delta_ms"] != 70.0 || r.Metrics["grade"] != "C" || r.Status != model.StatusWarn {
+		t.Fatalf("expected worst phase to drive the result, got %s %+v", r.Status, r.Metrics)
+	}
+}
+
+func TestBufferbloatFailsWithoutPingReplies(t *testing.T) {
+	fastRampUp(t)
+	c := cfg()
+	download := "iperf3 -c 10.0.0.2 -P 2 -t 12 -R"
+	upload := "iperf3 -c 10.0.0.2 -P 2 -t 12"
+	lost := "10 packets transmitted, 0 packets received, 100.0% packet loss\n"
+	fx := &execx.FakeExecutor{
+		Paths: map[string]bool{"ping": true, "iperf3": true},
+		Outputs: map[string]execx.Result{
+			download: {Stdout: "ok"},
+			upload:   {Stdout: "ok"},
+		},
+		Sequences: map[string][]execx.Result{
+			"ping -c 10 -- 1.1.1.1": {
+				{Stdout: pingTimes(10, 11, 12)},
+				{Stdout: lost, Err: errors.New("exit status 1")},
+				{Stdout: pingTimes(30, 41, 50)},
+			},
+		},
+		Delays: map[string]time.Duration{download: 150 * time.Millisecond, upload: 150 * time.Millisecond},
+	}
+	r := BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusFail || r.Metrics["grade"] != "F" || !strings.Contains(r.Error, "no ping replies under download load") {
+		t.Fatalf("expected a lost phase to fail with grade F, got %s err=%s %+v", r.Status, r.Error, r.Metrics)
+	}
+	if r.Metrics["download_loss_pct"] != 100.0 || r.Metrics["download_delta_ms"] != nil {
+		t.Fatalf("a lost phase should report loss and no delta: %+v", r.Metrics)
+	}
+	if r.Metrics["upload_delta_ms"] != 30.0 || r.Metrics["delta_ms"] != 30.0 {
+		t.Fatalf("the answered phase should still be measured: %+v", r.Metrics)
+	}
+
+	fx.Sequences = map[string][]execx.Result{"ping -c 10 -- 1.1.1.1": {{Stdout: pingTimes(10, 11, 12)}, {Stdout: lost}}}
+	fx.Calls = nil
+	r = BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusFail || r.Metrics["grade"] != "F" || r.Metrics["delta_ms"] != nil {
+		t.Fatalf("expected loss under both loads to fail without a delta, got %s %+v", r.Status, r.Metrics)
+	}
+
+	fx.Sequences = map[string][]execx.Result{"ping -c 10 -- 1.1.1.1": {{Stdout: lost, Err: errors.New("exit status 1")}}}
+	fx.Calls = nil
+	r = BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusFail || r.Metrics["grade"] != "F" || r.Metrics["idle_loss_pct"] != 100.0 || r.Error != "no idle ping replies" {
+		t.Fatalf("expected an unanswered idle ping to fail, got %s err=%s %+v", r.Status, r.Error, r.Metrics)
+	}
+	if len(fx.Calls) != 1 {
+		t.Fatalf("no load should start without an idle baseline, got %v", fx.Calls)
+	}
+}
+
+func TestBufferbloatSkipsWhenLoadEndsBeforeSampling(t *testing.T) {
+	fastRampUp(t)
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"ping": true, "iperf3": true}, Outputs: map[string]execx.Result{
+		"ping -c 10 -- 1.1.1.1": {Stdout: pingOK()},
+	}}
+	r := BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusSkip || !strings.Contains(r.Error, "download load ended before latency was sampled") {
+		t.Fatalf("expected skip without load, got %s err=%s", r.Status, r.Error)
+	}
+	if len(fx.Calls) != 3 {
+		t.Fatalf("loaded pings must not run without load, got %v", fx.Calls)
+	}
+}
+
+func TestBufferbloatLoadFollowsSpeedtestProvider(t *testing.T) {
+	fastRampUp(t)
+	c := cfg()
+	c.Bandwidth.Iperf.Target = ""
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"ping": true, "librespeed-cli": true}, Outputs: map[string]execx.Result{
+		"ping -c 10 -- 1.1.1.1": {Stdout: pingOK()},
+	}, Delays: map[string]time.Duration{
+		"librespeed-cli --json --no-upload":   200 * time.Millisecond,
+		"librespeed-cli --json --no-download": 200 * time.Millisecond,
+	}}
+	r := BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status == model.StatusSkip || r.Metrics["download_delta_ms"] == nil || r.Metrics["upload_delta_ms"] == nil {
+		t.Fatalf("expected librespeed-cli to load both phases, got %s err=%s calls=%v", r.Status, r.Error, fx.Calls)
+	}
+
+	c.Bandwidth.Speedtest.Provider = "ookla"
+	fx = &execx.FakeExecutor{Paths: map[string]bool{"ping": true, "speedtest": true}, Outputs: map[string]execx.Result{
+		"ping -c 10 -- 1.1.1.1": {Stdout: pingOK()},
+	}}
+	r = BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusSkip || r.Error != "no load generator: speedtest cannot run download and upload separately" {
+		t.Fatalf("expected skip for a provider that cannot split directions, got %s err=%s", r.Status, r.Error)
+	}
+
+	c = cfg()
+	fx = &execx.FakeExecutor{Paths: map[string]bool{"ping": true}, Outputs: map[string]execx.Result{
+		"ping -c 10 -- 1.1.1.1": {Stdout: pingOK()},
+	}}
+	r = BufferbloatCheck{Target: "1.1.1.1"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusSkip || r.Error != "no load generator: iperf3 not found; no speedtest provider found (speedtest-cli, speedtest, librespeed-cli)" {
+		t.Fatalf("expected missing tools to skip before any load starts, got %s err=%s", r.Status, r.Error)
+	}
+	if len(fx.Calls) != 1 {
+		t.Fatalf("only the idle ping should run, got %v", fx.Calls)
+	}
+}
+
+func TestLoadPhasesRampUpPerGenerator(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"iperf3": true, "speedtest-cli": true}}
+	iperf, _ := loadPhases(fx, c)
+	for _, ph := range iperf {
+		if ph.RampUp != iperfRampUp {
+			t.Fatalf("iperf3 %s phase: expected %s ramp-up, got %s", ph.Name, iperfRampUp, ph.RampUp)
+		}
+	}
+	c.Bandwidth.Iperf.Target = ""
+	speedtest, _ := loadPhases(fx, c)
+	for _, ph := range speedtest {
+		if ph.RampUp != speedtestRampUp {
+			t.Fatalf("speedtest %s phase: expected %s ramp-up, got %s", ph.Name, speedtestRampUp, ph.RampUp)
+		}
+	}
+	if speedtestRampUp <= iperfRampUp {
+		t.Fatalf("speedtest ramp-up must cover server selection and its latency test")
+	}
+}
+
+func fastRampUp(t *testing.T) {
+	t.Helper()
+	iperf, speedtest := iperfRampUp, speedtestRampUp
+	iperfRampUp, speedtestRampUp = 10*time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() { iperfRampUp, speedtestRampUp = iperf, speedtest })
+}
+
+func pingTimes(ms ...float64) string {
+	var b strings.Builder
+	for i, v := range ms {
+		fmt.Fprintf(&b, "64 bytes from 1.1.1.1: icmp_seq=%d ttl=57 time=%.1f ms\n", i, v)
+	}
+	fmt.Fprintf(&b, "%d packets transmitted, %d packets received, 0.0%% packet loss\n", len(ms), len(ms))
+	return b.String()
+}
+
+func TestLocalCheckLinuxUsesIPRoute2(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"ip": true, "ping": true}, Outputs: map[string]execx.Result{
+		"ip -j route show default": {Stdout: `[{"dst":"default","gateway":"10.0.0.1","dev":"eth0"}]`},
+		"ip -j addr show":          {Stdout: `[{"ifname":"lo","flags":["LOOPBACK","UP"],"operstate":"UNKNOWN","addr_info":[{"family":"inet","local":"127.0.0.1"}]},{"ifname":"eth0","flags":["UP"],"operstate":"UP","addr_info":[{"family":"inet","local":"10.0.0.5"},{"family":"inet6","local":"fe80::1"}]}]`},
+		"ping -c 10 -- 10.0.0.1":   {Stdout: pingOK()},
+	}}
+	r := LocalCheck{OS: "linux"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusPass || r.Target != "10.0.0.1" {
+		t.Fatalf("expected pass via 10.0.0.1, got %s target=%q err=%s", r.Status, r.Target, r.Error)
+	}
+	if r.Metrics["interface"] != "eth0" || r.Metrics["link_state"] != "UP" {
+		t.Fatalf("unexpected interface metrics: %+v", r.Metrics)
+	}
+	if r.Metrics["active_interfaces"] != 1 || r.Metrics["local_ip_count"] != 1 {
+		t.Fatalf("unexpected interface counts: %+v", r.Metrics)
+	}
+	addrs, _ := r.Metrics["interface_addrs"].([]string)
+	if len(addrs) != 2 || addrs[0] != "10.0.0.5" {
+		t.Fatalf("unexpected interface addrs: %+v", r.Metrics["interface_addrs"])
+	}
+}
+
+func TestLocalCheckLinuxFallsBackToProcRoute(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"cat": true, "ping": true}, Outputs: map[string]execx.Result{
+		"cat /proc/net/route":                {Stdout: "Iface\tDestination\tGateway\nwlan0\t00000000\t0101A8C0\t0003\n"},
+		"cat /sys/class/net/wlan0/operstate": {Stdout: "up\n"},
+		"ping -c 10 -- 192.168.1.1":          {Stdout: pingOK()},
+	}}
+	r := LocalCheck{OS: "linux"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusPass || r.Target != "192.168.1.1" {
+		t.Fatalf("expected pass via 192.168.1.1, got %s target=%q err=%s", r.Status, r.Target, r.Error)
+	}
+	if r.Metrics["interface"] != "wlan0" || r.Metrics["link_state"] != "UP" {
+		t.Fatalf("unexpected interface metrics: %+v", r.Metrics)
+	}
+}
+
+func TestLocalCheckLinuxFallsBackWhenIPHasNoJSON(t *testing.T) {
+	cases := []struct {
+		name, ifconfig string
+	}{
+		{"busybox", "eth0      Link encap:Ethernet  HWaddr 02:42:AC:11:00:02\n          inet addr:172.17.0.2  Bcast:172.17.255.255  Mask:255.255.0.0\n          UP BROADCAST RUNNING MULTICAST  MTU:1500  Metric:1\n\n" +
+			"eth1      Link encap:Ethernet  HWaddr 02:42:AC:11:00:03\n          BROADCAST MULTICAST  MTU:1500  Metric:1\n\n" +
+			"lo        Link encap:Local Loopback\n          inet addr:127.0.0.1  Mask:255.0.0.0\n          UP LOOPBACK RUNNING  MTU:65536  Metric:1\n"},
+		{"net-tools", "eth0: flags=4163<UP,BROADCAST,RUNNING,MULTICAST>  mtu 1500\n        inet 172.17.0.2  netmask 255.255.0.0  broadcast 172.17.255.255\n\n" +
+			"eth1: flags=4098<BROADCAST,MULTICAST>  mtu 1500\n\n" +
+			"lo: flags=73<UP,LOOPBACK,RUNNING>  mtu 65536\n        inet 127.0.0.1  netmask 255.0.0.0\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := cfg()
+			noJSON := execx.Result{Err: errors.New("exit status 1"), Stderr: "ip: invalid option -- 'j'", ExitCode: 1}
+			fx := &execx.FakeExecutor{Paths: map[string]bool{"ip": true, "cat": true, "ifconfig": true, "ping": true}, Outputs: map[string]execx.Result{
+				"ip -j route show default":          noJSON,
+				"ip -j addr show":                   noJSON,
+				"cat /proc/net/route":               {Stdout: "Iface\tDestination\tGateway\neth0\t00000000\t010011AC\t0003\n"},
+				"cat /sys/class/net/eth0/operstate": {Stdout: "up\n"},
+				"ifconfig":                          {Stdout: tc.ifconfig},
+				"ping -c 10 -- 172.17.0.1":          {Stdout: pingOK()},
+			}}
+			r := LocalCheck{OS: "linux"}.Run(context.Background(), fx, c, 2)
+			if r.Status != model.StatusPass || r.Target != "172.17.0.1" {
+				t.Fatalf("expected pass via 172.17.0.1, got %s target=%q err=%s", r.Status, r.Target, r.Error)
+			}
+			if r.Metrics["link_state"] != "UP" || r.Metrics["active_interfaces"] != 1 || r.Metrics["local_ip_count"] != 1 {
+				t.Fatalf("unexpected interface metrics: %+v", r.Metrics)
+			}
+		})
+	}
+}
+
+func TestLocalCheckLinuxFallsBackToNetstat(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{"cat": true, "netstat": true, "ping": true}, Outputs: map[string]execx.Result{
+		"cat /proc/net/route":    {Err: errors.New("exit status 1"), ExitCode: 1},
+		"netstat -rn":            {Stdout: "Kernel IP routing table\nDestination     Gateway         Genmask         Flags   MSS Window  irtt Iface\n0.0.0.0         10.0.0.1        0.0.0.0         UG        0 0          0 eth0\n"},
+		"ping -c 10 -- 10.0.0.1": {Stdout: pingOK()},
+	}}
+	r := LocalCheck{OS: "linux"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusPass || r.Target != "10.0.0.1" {
+		t.Fatalf("expected pass via 10.0.0.1, got %s target=%q err=%s", r.Status, r.Target, r.Error)
+	}
+}
+
+func TestLocalCheckLinuxSkipWhenNoTools(t *testing.T) {
+	c := cfg()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{}}
+	r := LocalCheck{OS: "linux"}.Run(context.Background(), fx, c, 2)
+	if r.Status != model.StatusSkip {
+		t.Fatalf("expected skip, got %s", r.Status)
+	}
+}
+
+func TestDNSNativeEngineRecordsAnswerMetadata(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{TTL: 120, Addrs: []string{"142.250.0.1"}}
+	})
+	defer srv.Close()
+	c := cfg()
+	r := DNSCheck{Domain: "google.com", Resolver: srv.Addr}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusPass {
+		t.Fatalf("expected pass, got %s (err=%s)", r.Status, r.Error)
+	}
+	if r.Metrics["rcode"] != "NOERROR" || r.Metrics["answer_count"] != 1 || r.Metrics["min_ttl"] != 120 || r.Metrics["transport"] != "udp" {
+		t.Fatalf("unexpected metrics: %+v", r.Metrics)
+	}
+	if ms, _ := r.Metrics["query_ms"].(float64); ms <= 0 {
+		t.Fatalf("expected sub-millisecond timing > 0, got %v", r.Metrics["query_ms"])
+	}
+}
+
+func TestDNSNativeEngineFailsOnNXDOMAIN(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{Rcode: 3}
+	})
+	defer srv.Close()
+	r := DNSCheck{Domain: "missing.example", Resolver: srv.Addr}.Run(context.Background(), &execx.FakeExecutor{}, cfg(), 2)
+	if r.Status != model.StatusFail || r.Error != "rcode NXDOMAIN" {
+		t.Fatalf("expected NXDOMAIN fail, got %s (err=%s)", r.Status, r.Error)
+	}
+}
+
+func TestDNSNativeEngineUsesSystemResolver(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{TTL: 30, Addrs: []string{"10.0.0.9"}}
+	})
+	defer srv.Close()
+	p := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(p, []byte("nameserver "+srv.Addr+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prev := resolvConfPath
+	resolvConfPath = p
+	defer func() { resolvConfPath = prev }()
+	r := DNSCheck{Domain: "google.com"}.Run(context.Background(), &execx.FakeExecutor{}, cfg(), 2)
+	if r.Status != model.StatusPass || r.Metrics["server"] != srv.Addr {
+		t.Fatalf("expected pass via system resolver, got %s metrics=%+v err=%s", r.Status, r.Metrics, r.Error)
+	}
+}
+
+func TestHTTPNativeEngineRecordsTimings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	fx := &execx.FakeExecutor{Paths: map[string]bool{}}
+	r := HTTPCheck{URL: srv.URL, Engine: "native"}.Run(context.Background(), fx, cfg(), 2)
+	if r.Status != model.StatusPass {
+		t.Fatalf("expected pass, got %s (err=%s)", r.Status, r.Error)
+	}
+	if r.Metrics["status_code"] != 200 || r.Metrics["http_proto"] != "HTTP/1.1" {
+		t.Fatalf("unexpected metrics: %+v", r.Metrics)
+	}
+	if total, _ := r.Metrics["total_ms"].(float64); total <= 0 {
+		t.Fatalf("expected total_ms > 0, got %v", r.Metrics["total_ms"])
+	}
+	if len(fx.Calls) != 0 {
+		t.Fatalf("native engine must not exec tools, got calls %v", fx.Calls)
+	}
+}
+
+func TestHTTPNativeEngineWarnsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	c := cfg()
+	c.HTTP.NativeURLs = []string{srv.URL}
+	r := HTTPCheck{URL: srv.URL}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusWarn || r.Error != "http status 404" {
+		t.Fatalf("expected warn for 404, got %s (err=%s)", r.Status, r.Error)
+	}
+}
+
+func tlsServerWithBundle(t *testing.T) (*httptest.Server, config.Config) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, pemBytes, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := cfg()
+	c.TLS.CABundle = bundle
+	return srv, c
+}
+
+func TestTLSCheckValidChainPasses(t *testing.T) {
+	srv, c := tlsServerWithBundle(t)
+	r := TLSCheck{URL: srv.URL}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusPass {
+		t.Fatalf("expected pass, got %s (err=%s)", r.Status, r.Error)
+	}
+	if r.Metrics["chain_valid"] != true || r.Metrics["hostname_valid"] != true {
+		t.Fatalf("unexpected metrics: %+v", r.Metrics)
+	}
+	if days, _ := r.Metrics["days_until_expiry"].(float64); days <= 0 {
+		t.Fatalf("expected positive expiry days, got %v", r.Metrics["days_until_expiry"])
+	}
+}
+
+func TestTLSCheckExpiryThresholds(t *testing.T) {
+	srv, c := tlsServerWithBundle(t)
+	c.Thresholds.TLSExpiryPassDays = 1_000_000
+	c.Thresholds.TLSExpiryWarnDays = 7
+	r := TLSCheck{URL: srv.URL}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusWarn {
+		t.Fatalf("expected warn, got %s (err=%s)", r.Status, r.Error)
+	}
+	c.Thresholds.TLSExpiryWarnDays = 1_000_000
+	r = TLSCheck{URL: srv.URL}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusFail {
+		t.Fatalf("expected fail, got %s (err=%s)", r.Status, r.Error)
+	}
+}
+
+func TestTLSCheckUntrustedChainFails(t *testing.T) {
+	srv, c := tlsServerWithBundle(t)
+	c.TLS.CABundle = ""
+	r := TLSCheck{URL: srv.URL}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusFail || r.Metrics["chain_valid"] != false {
+		t.Fatalf("expected chain failure, got %s metrics=%+v", r.Status, r.Metrics)
+	}
+}
+
+func TestTLSCheckHostnameMismatchFails(t *testing.T) {
+	srv, c := tlsServerWithBundle(t)
+	u := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	r := TLSCheck{URL: u}.Run(context.Background(), &execx.FakeExecutor{}, c, 2)
+	if r.Status != model.StatusFail || r.Metrics["hostname_valid"] != false {
+		t.Fatalf("expected hostname failure, got %s metrics=%+v err=%s", r.Status, r.Metrics, r.Error)
+	}
+}
+
+func TestTargetsCannotBecomeOptions(t *testing.T) {
+	c := cfg()
+	c.Bandwidth.Iperf.Enabled = false
+	c.Bandwidth.Speedtest.Enabled = false
+	c.DNS.Engine = "dig"
+	hostile := "-oProxyCommand=sh"
+	iperf := cfg()
+	iperf.Bandwidth.Iperf.Target = hostile
+	cases := []struct {
+		name  string
+		check Check
+		cfg   config.Config
+		paths string
+		want  string
+	}{
+		{"reachability", ReachabilityCheck{Target: hostile}, c, "ping", "ping -c 10 -- " + hostile},
+		{"bufferbloat", BufferbloatCheck{Target: hostile}, c, "ping", "ping -c 10 -- " + hostile},
+		{"path", PathCheck{Target: hostile}, c, "mtr", "mtr -rwzc 10 -- " + hostile},
+		{"traceroute", PathCheck{Target: hostile}, c, "traceroute", "traceroute -m 15 -- " + hostile},
+		{"dig", DNSCheck{Domain: hostile}, c, "dig", "dig -q " + hostile},
+		{"dig resolver", DNSCheck{Domain: hostile, Resolver: "1.1.1.1"}, c, "dig", "dig @1.1.1.1 -q " + hostile},
+		// -c takes the next argument as its value, so the target cannot become a flag.
+		{"iperf3", IperfCheck{}, iperf, "iperf3", "iperf3 -c " + hostile + " -P 4 -t 30 -J"},
+		{"http", HTTPCheck{URL: "--output=/tmp/x", Engine: "curl"}, c, "curl", "-s --url --output=/tmp/x"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fx := &execx.FakeExecutor{Paths: map[string]bool{tc.paths: true}}
+			tc.check.Run(context.Background(), fx, tc.cfg, 1)
+			found := false
+			for _, call := range fx.Calls {
+				found = found || strings.HasSuffix(call, tc.want)
+			}
+			if !found {
+				t.Fatalf("expected a call ending in %q, got %q", tc.want, fx.Calls)
+			}
+		})
+	}
+}
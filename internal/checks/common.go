@@ -7,6 +7,9 @@ import (
 	"time"
 )
 
+// notRecorded is the skip reason for in-process checks during --replay.
+const notRecorded = "not recorded in cassette"
+
 func runWithTimeout(parent context.Context, timeoutSec int, ex execx.Executor, name string, args ...string) execx.Result {
 	t := time.Duration(timeoutSec) * time.Second
 	if t <= 0 {
@@ -17,6 +20,12 @@ func runWithTimeout(parent context.Context, timeoutSec int, ex execx.Executor, n
 	return ex.Run(ctx, name, args...)
 }
 
+// pingArgs ends option parsing before the target; config validation already
+// rejects targets that look like options, this keeps argv safe regardless.
+func pingArgs(target string) []string {
+	return []string{"-c", "10", "--", target}
+}
+
 func isInterruptedError(err error) bool {
 	if err == nil {
 		return false
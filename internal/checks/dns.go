@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"netcheck/internal/config"
+	"netcheck/internal/dnsx"
 	"netcheck/internal/eval"
 	"netcheck/internal/execx"
 	"netcheck/internal/model"
 	"time"
 )
 
+// resolvConfPath is the source of system nameservers for the native engine.
+var resolvConfPath = "/etc/resolv.conf"
+
 type DNSCheck struct {
 	Domain   string
 	Resolver string
@@ -23,16 +27,107 @@ func (c DNSCheck) ID() string {
 }
 func (c DNSCheck) Group() string { return "dns" }
 
+// DependsOn prefers reachability to an explicit resolver so a blocked lookup
+// points at the unreachable resolver rather than straight at the gateway.
+func (c DNSCheck) DependsOn() []string {
+	if c.Resolver == "" {
+		return gatewayDependency
+	}
+	return []string{ReachabilityCheck{Target: c.Resolver}.ID(), LocalCheck{}.ID()}
+}
+
+func (c DNSCheck) target() string {
+	if c.Resolver != "" {
+		return c.Domain + " via " + c.Resolver
+	}
+	return c.Domain
+}
+
 func (c DNSCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
+	// A replay only has the dig answers a cassette recorded.
+	if cfg.DNS.Engine == "dig" || execx.Replaying(ex) {
+		return c.runDig(ctx, ex, cfg, timeoutSec)
+	}
+	return c.runNative(ctx, cfg, timeoutSec)
+}
+
+func (c DNSCheck) runNative(ctx context.Context, cfg config.Config, timeoutSec int) model.CheckResult {
+	start := time.Now()
+	target := c.target()
+	server := c.Resolver
+	if server == "" {
+		servers := dnsx.SystemServers(resolvConfPath)
+		if len(servers) == 0 {
+			return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: target, Status: model.StatusFail, Error: "no system nameserver found in " + resolvConfPath}
+		}
+		server = servers[0]
+	}
+	qtype := dnsx.TypeA
+	if cfg.DNS.RecordType == "AAAA" {
+		qtype = dnsx.TypeAAAA
+	}
+	t := time.Duration(timeoutSec) * time.Second
+	if t <= 0 {
+		t = 20 * time.Second
+	}
+	qctx, cancel := context.WithTimeout(ctx, t)
+	defer cancel()
+	execx.Logf(ctx, "op", "performing DNS lookup; native query %s %s @%s", cfg.DNS.RecordType, c.Domain, server)
+	res, err := dnsx.Query(qctx, server, c.Domain, qtype)
+	if err != nil {
+		execx.Logf(ctx, "op", "query error: %v", err)
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: target, Status: model.StatusFail, Error: err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	ms := float64(res.RTT.Microseconds()) / 1000
+	execx.Logf(ctx, "op", "logs: rcode=%s answers=%d transport=%s time=%.3fms", res.RcodeName(), res.Answers, res.Transport, ms)
+	ttls := make([]int, 0, len(res.TTLs))
+	minTTL := 0
+	for i, ttl := range res.TTLs {
+		ttls = append(ttls, int(ttl))
+		if i == 0 || int(ttl) < minTTL {
+			minTTL = int(ttl)
+		}
+	}
+	metrics := map[string]any{
+		"query_ms":     ms,
+		"rcode":        res.RcodeName(),
+		"answer_count": res.Answers,
+		"ttls":         ttls,
+		"min_ttl":      minTTL,
+		"truncated":    res.Truncated,
+		"transport":    res.Transport,
+		"server":       server,
+	}
+	if len(res.Addrs) > 0 {
+		metrics["addrs"] = res.Addrs
+	}
+	status := eval.LowerIsBetter(ms, cfg.Thresholds.DNSPassMaxMs, cfg.Thresholds.DNSWarnMaxMs)
+	errMsg := ""
+	switch {
+	case res.Rcode != 0:
+		status = model.StatusFail
+		errMsg = "rcode " + res.RcodeName()
+	case res.Answers == 0:
+		status = model.StatusWarn
+		errMsg = "no answers"
+	}
+	return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: target, Status: status, Metrics: metrics, Error: errMsg, DurationMS: time.Since(start).Milliseconds()}
+}
+
+func (c DNSCheck) runDig(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
 	if _, err := ex.LookPath("dig"); err != nil {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Domain, Status: model.StatusSkip, Error: "dig not found"}
 	}
-	args := []string{c.Domain}
-	target := c.Domain
+	// dig has no "--"; -q names the query explicitly, so the domain is never
+	// read as an option.
+	args := []string{"-q", c.Domain}
+	target := c.target()
 	if c.Resolver != "" {
-		args = []string{"@" + c.Resolver, c.Domain}
-		target = c.Domain + " via " + c.Resolver
+		args = []string{"@" + c.Resolver, "-q", c.Domain}
+	}
+	if cfg.DNS.RecordType == "AAAA" {
+		args = append(args, "AAAA")
 	}
 	res := runWithTimeout(ctx, timeoutSec, ex, "dig", args...)
 	if isInterruptedError(res.Err) {
@@ -2,27 +2,100 @@ package checks
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/url"
 	"netcheck/internal/config"
 	"netcheck/internal/eval"
 	"netcheck/internal/execx"
+	"netcheck/internal/httpx"
 	"netcheck/internal/model"
 	"strings"
 	"time"
 )
 
-type HTTPCheck struct{ URL string }
+// HTTPCheck times one URL. Engine is "curl" or "native"; empty defers to cfg.
+type HTTPCheck struct {
+	URL    string
+	Engine string
+}
 
-func (c HTTPCheck) ID() string    { return "http." + c.URL }
-func (c HTTPCheck) Group() string { return "http" }
+func (c HTTPCheck) ID() string          { return "http." + c.URL }
+func (c HTTPCheck) Group() string       { return "http" }
+func (c HTTPCheck) DependsOn() []string { return gatewayDependency }
 
 func (c HTTPCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
+	engine := c.Engine
+	if engine == "" {
+		engine = cfg.HTTPEngineFor(c.URL)
+	}
+	// A replay only has the curl output a cassette recorded.
+	if engine == "native" && !execx.Replaying(ex) {
+		return c.runNative(ctx, cfg, timeoutSec)
+	}
+	return c.runCurl(ctx, ex, cfg, timeoutSec)
+}
+
+func (c HTTPCheck) runNative(ctx context.Context, cfg config.Config, timeoutSec int) model.CheckResult {
+	start := time.Now()
+	t := time.Duration(timeoutSec) * time.Second
+	if t <= 0 {
+		t = 20 * time.Second
+	}
+	pctx, cancel := context.WithTimeout(ctx, t)
+	defer cancel()
+	execx.Logf(ctx, "op", "measuring HTTP/TLS timings; native GET %s", c.URL)
+	roots, err := loadRootCAs(cfg.TLS.CABundle)
+	if err != nil {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusFail, Error: err.Error()}
+	}
+	res, err := httpx.Probe(pctx, c.URL, httpx.Options{RootCAs: roots})
+	if err != nil {
+		execx.Logf(ctx, "op", "request error: %v", err)
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusFail, Error: err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	total := durMS(res.Total)
+	execx.Logf(ctx, "op", "logs: status=%d proto=%s total=%.3fms", res.StatusCode, res.Proto, total)
+	metrics := map[string]any{
+		"dns_ms":      durMS(res.DNS),
+		"connect_ms":  durMS(res.Connect),
+		"tls_ms":      durMS(res.TLS),
+		"ttfb_ms":     durMS(res.TTFB),
+		"total_ms":    total,
+		"status_code": res.StatusCode,
+		"http_proto":  res.Proto,
+	}
+	if res.TLSVersion != "" {
+		metrics["tls_protocol"] = res.TLSVersion
+		metrics["tls_cipher"] = res.CipherSuite
+		metrics["tls_alpn"] = res.ALPN
+		chain := make([]string, 0, len(res.PeerCerts))
+		for _, cert := range res.PeerCerts {
+			chain = append(chain, cert.Subject.String())
+		}
+		metrics["tls_cert_chain"] = chain
+	}
+	status := eval.LowerIsBetter(total, cfg.Thresholds.HTTPPassMaxMs, cfg.Thresholds.HTTPWarnMaxMs)
+	errMsg := ""
+	if res.StatusCode >= 400 {
+		if status == model.StatusPass {
+			status = model.StatusWarn
+		}
+		errMsg = fmt.Sprintf("http status %d", res.StatusCode)
+	}
+	return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: status, Metrics: metrics, Error: errMsg, DurationMS: time.Since(start).Milliseconds()}
+}
+
+func durMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func (c HTTPCheck) runCurl(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
 	if _, err := ex.LookPath("curl"); err != nil {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusSkip, Error: "curl not found"}
 	}
-	res := runWithTimeout(ctx, timeoutSec, ex, "curl", "-w", "dns:%{time_namelookup} connect:%{time_connect} tls:%{time_appconnect} ttfb:%{time_starttransfer} total:%{time_total}", "-o", "/dev/null", "-s", c.URL)
+	res := runWithTimeout(ctx, timeoutSec, ex, "curl", "-w", "dns:%{time_namelookup} connect:%{time_connect} tls:%{time_appconnect} ttfb:%{time_starttransfer} total:%{time_total}", "-o", "/dev/null", "-s", "--url", c.URL)
 	if isInterruptedError(res.Err) {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
@@ -6,60 +6,239 @@ import (
 	"netcheck/internal/eval"
 	"netcheck/internal/execx"
 	"netcheck/internal/model"
+	"slices"
 	"strings"
 	"time"
 )
 
-type LocalCheck struct{}
+// LocalCheck pings the default gateway and reports local interface state.
+// OS selects the discovery backend; empty means the executor's platform.
+type LocalCheck struct{ OS string }
 
 func (LocalCheck) ID() string    { return "local.gateway" }
 func (LocalCheck) Group() string { return "local" }
 
-func (LocalCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
+// localInfo is the platform-neutral result of gateway and interface discovery.
+type localInfo struct {
+	Gateway          string
+	Interface        string
+	Addrs            []string
+	LinkState        string
+	ActiveInterfaces int
+	LocalIPCount     int
+	HasInterfaceData bool
+}
+
+func (c LocalCheck) goos(ex execx.Executor) string {
+	if c.OS != "" {
+		return c.OS
+	}
+	return execx.GOOS(ex)
+}
+
+func (c LocalCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
-	if _, err := ex.LookPath("netstat"); err != nil {
-		return model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusSkip, Error: "netstat not found"}
+	var info localInfo
+	var err error
+	var skipReason string
+	if c.goos(ex) == "linux" {
+		info, skipReason, err = discoverLinux(ctx, ex, timeoutSec)
+	} else {
+		info, skipReason, err = discoverDarwin(ctx, ex, timeoutSec)
 	}
-	res := runWithTimeout(ctx, timeoutSec, ex, "netstat", "-rn")
-	if res.Err != nil {
-		return model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusFail, Error: res.Err.Error(), DurationMS: time.Since(start).Milliseconds()}
-	}
-	gw := ""
-	for _, line := range strings.Split(res.Stdout, "\n") {
-		if strings.HasPrefix(strings.TrimSpace(line), "default") {
-			fields := strings.Fields(line)
-			if len(fields) > 1 {
-				gw = fields[1]
-				break
-			}
-		}
+	if skipReason != "" {
+		return model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusSkip, Error: skipReason}
 	}
+	if err != nil {
+		return model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusFail, Error: err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	gw := info.Gateway
 	if gw == "" {
 		return model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusWarn, Error: "default gateway not detected", DurationMS: time.Since(start).Milliseconds()}
 	}
-	ping := runWithTimeout(ctx, timeoutSec, ex, "ping", "-c", "10", gw)
+	ping := runWithTimeout(ctx, timeoutSec, ex, "ping", pingArgs(gw)...)
 	if isInterruptedError(ping.Err) {
 		return model.CheckResult{ID: "local.gateway", Group: "local", Target: gw, Status: model.StatusFail, Error: ping.Err.Error(), Raw: ping.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
 	loss, avg, jitter, _ := parsePing(ping.Stdout)
 	status := eval.LowerIsBetter(loss, cfg.Thresholds.LossPassMax, cfg.Thresholds.LossWarnMax)
 	metrics := map[string]any{"loss_pct": loss, "avg_ms": avg, "jitter_ms": jitter}
+	if info.HasInterfaceData {
+		metrics["active_interfaces"] = info.ActiveInterfaces
+		metrics["local_ip_count"] = info.LocalIPCount
+	}
+	if info.Interface != "" {
+		metrics["interface"] = info.Interface
+	}
+	if len(info.Addrs) > 0 {
+		metrics["interface_addrs"] = info.Addrs
+	}
+	if info.LinkState != "" {
+		metrics["link_state"] = info.LinkState
+	}
+	return model.CheckResult{ID: "local.gateway", Group: "local", Target: gw, Status: status, Metrics: metrics, Raw: ping.Stdout, DurationMS: time.Since(start).Milliseconds()}
+}
+
+// discoverDarwin reads the default route from netstat and interface state from ifconfig.
+func discoverDarwin(ctx context.Context, ex execx.Executor, timeoutSec int) (localInfo, string, error) {
+	var info localInfo
+	if _, err := ex.LookPath("netstat"); err != nil {
+		return info, "netstat not found", nil
+	}
+	res := runWithTimeout(ctx, timeoutSec, ex, "netstat", "-rn")
+	if res.Err != nil {
+		return info, "", res.Err
+	}
+	info.Gateway, info.Interface = parseNetstatDefaultRoute(res.Stdout)
+	if _, err := ex.LookPath("ifconfig"); err == nil {
+		ifcfg := runWithTimeout(ctx, timeoutSec, ex, "ifconfig")
+		applyIfconfig(&info, ifcfg.Stdout)
+	}
+	return info, "", nil
+}
+
+// linuxRouteSources are tried in order until one yields a default gateway.
+// BusyBox and older iproute2 have no -j, so a failing ip falls through.
+var linuxRouteSources = []struct {
+	name  string
+	args  []string
+	parse func(string) (gateway, iface string)
+}{
+	{"ip", []string{"-j", "route", "show", "default"}, parseIPRouteJSON},
+	{"cat", []string{"/proc/net/route"}, parseProcNetRoute},
+	{"netstat", []string{"-rn"}, parseNetstatDefaultRoute},
+}
+
+// discoverLinux prefers iproute2 JSON output, then /proc and /sys, then net-tools.
+func discoverLinux(ctx context.Context, ex execx.Executor, timeoutSec int) (localInfo, string, error) {
+	var info localInfo
+	found, read := false, false
+	var routeErr error
+	for _, src := range linuxRouteSources {
+		if _, err := ex.LookPath(src.name); err != nil {
+			continue
+		}
+		found = true
+		res := runWithTimeout(ctx, timeoutSec, ex, src.name, src.args...)
+		if res.Err != nil {
+			routeErr = res.Err
+			continue
+		}
+		read = true
+		if info.Gateway, info.Interface = src.parse(res.Stdout); info.Gateway != "" {
+			break
+		}
+	}
+	if !found {
+		return info, "ip, /proc/net/route and netstat not available", nil
+	}
+	// Only fail when no source could be read; a readable table without a
+	// default route is reported as an undetected gateway.
+	if !read {
+		return info, "", routeErr
+	}
+	if info.Gateway == "" {
+		return info, "", nil
+	}
+	if _, err := ex.LookPath("ip"); err == nil {
+		addr := runWithTimeout(ctx, timeoutSec, ex, "ip", "-j", "addr", "show")
+		if addr.Err == nil {
+			applyIPAddrJSON(&info, addr.Stdout)
+			return info, "", nil
+		}
+	}
+	if _, err := ex.LookPath("cat"); err == nil && info.Interface != "" {
+		state := runWithTimeout(ctx, timeoutSec, ex, "cat", "/sys/class/net/"+info.Interface+"/operstate")
+		if state.Err == nil {
+			info.LinkState = strings.ToUpper(strings.TrimSpace(state.Stdout))
+		}
+	}
 	if _, err := ex.LookPath("ifconfig"); err == nil {
 		ifcfg := runWithTimeout(ctx, timeoutSec, ex, "ifconfig")
-		lines := strings.Split(ifcfg.Stdout, "\n")
-		active := 0
-		ipCount := 0
-		for _, l := range lines {
-			t := strings.TrimSpace(l)
-			if strings.HasPrefix(t, "status: active") {
-				active++
+		if ifcfg.Err == nil {
+			applyLinuxIfconfig(&info, ifcfg.Stdout)
+		}
+	}
+	return info, "", nil
+}
+
+// applyIfconfig parses macOS ifconfig, which reports "status: active" per interface.
+func applyIfconfig(info *localInfo, raw string) {
+	active := 0
+	ipCount := 0
+	for _, l := range strings.Split(raw, "\n") {
+		t := strings.TrimSpace(l)
+		if strings.HasPrefix(t, "status: active") {
+			active++
+		}
+		if strings.HasPrefix(t, "inet ") && !strings.Contains(t, "127.0.0.1") {
+			ipCount++
+		}
+	}
+	info.ActiveInterfaces = active
+	info.LocalIPCount = ipCount
+	info.HasInterfaceData = true
+}
+
+func applyIPAddrJSON(info *localInfo, raw string) {
+	ifaces, err := parseIPAddrJSON(raw)
+	if err != nil {
+		return
+	}
+	info.HasInterfaceData = true
+	for _, ifc := range ifaces {
+		loopback := ifc.isLoopback()
+		if !loopback && ifc.OperState == "UP" {
+			info.ActiveInterfaces++
+		}
+		for _, a := range ifc.AddrInfo {
+			if a.Family == "inet" && !loopback && !strings.HasPrefix(a.Local, "127.") {
+				info.LocalIPCount++
 			}
-			if strings.HasPrefix(t, "inet ") && !strings.Contains(t, "127.0.0.1") {
-				ipCount++
+		}
+		if ifc.IfName == info.Interface {
+			info.LinkState = ifc.OperState
+			for _, a := range ifc.AddrInfo {
+				info.Addrs = append(info.Addrs, a.Local)
 			}
 		}
-		metrics["active_interfaces"] = active
-		metrics["local_ip_count"] = ipCount
 	}
-	return model.CheckResult{ID: "local.gateway", Group: "local", Target: gw, Status: status, Metrics: metrics, Raw: ping.Stdout, DurationMS: time.Since(start).Milliseconds()}
+}
+
+// applyLinuxIfconfig parses net-tools ifconfig in both the "eth0: flags=4163<UP,...>"
+// layout and the BusyBox one with a separate "UP BROADCAST RUNNING" line. An
+// interface counts as active when it is up, running and not loopback.
+func applyLinuxIfconfig(info *localInfo, raw string) {
+	var flags []string
+	active := func() {
+		if slices.Contains(flags, "UP") && slices.Contains(flags, "RUNNING") && !slices.Contains(flags, "LOOPBACK") {
+			info.ActiveInterfaces++
+		}
+		flags = nil
+	}
+	for _, l := range strings.Split(raw, "\n") {
+		f := strings.Fields(l)
+		if len(f) == 0 {
+			continue
+		}
+		if l[0] != ' ' && l[0] != '\t' {
+			active()
+			if i := strings.Index(l, "<"); i >= 0 {
+				if j := strings.Index(l[i:], ">"); j > 0 {
+					flags = strings.Split(l[i+1:i+j], ",")
+				}
+			}
+			continue
+		}
+		switch {
+		case f[0] == "inet" && len(f) > 1:
+			if !strings.HasPrefix(strings.TrimPrefix(f[1], "addr:"), "127.") {
+				info.LocalIPCount++
+			}
+		case f[0] == "UP":
+			flags = append(flags, f...)
+		}
+	}
+	active()
+	info.HasInterfaceData = true
 }
@@ -0,0 +1,70 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"netcheck/internal/config"
+	"netcheck/internal/execx"
+	"netcheck/internal/model"
+	"time"
+)
+
+// NetworkQualityCheck runs macOS networkQuality, which measures throughput
+// and responsiveness in one pass.
+type NetworkQualityCheck struct{}
+
+func (NetworkQualityCheck) ID() string          { return "bandwidth.networkquality" }
+func (NetworkQualityCheck) Group() string       { return "bandwidth" }
+func (NetworkQualityCheck) DependsOn() []string { return gatewayDependency }
+
+// networkQualityReport is the subset of `networkQuality -c` output we use.
+// Throughput is in bits per second, base_rtt in milliseconds and
+// responsiveness in round-trips per minute; the per-direction values only
+// exist on macOS 13 and later.
+type networkQualityReport struct {
+	DLThroughput     *float64 `json:"dl_throughput"`
+	ULThroughput     *float64 `json:"ul_throughput"`
+	BaseRTT          *float64 `json:"base_rtt"`
+	Responsiveness   *float64 `json:"responsiveness"`
+	DLResponsiveness *float64 `json:"dl_responsiveness"`
+	ULResponsiveness *float64 `json:"ul_responsiveness"`
+}
+
+func (c NetworkQualityCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
+	start := time.Now()
+	if _, err := ex.LookPath("networkQuality"); err != nil {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Status: model.StatusSkip, Error: "networkQuality not found"}
+	}
+	res := runWithTimeout(ctx, max(timeoutSec, 45), ex, "networkQuality", "-c")
+	if isInterruptedError(res.Err) {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	}
+	if res.Err != nil && res.Stdout == "" {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Status: model.StatusFail, Error: res.Err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	var nq networkQualityReport
+	if err := json.Unmarshal([]byte(res.Stdout), &nq); err != nil || nq.DLThroughput == nil {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Status: model.StatusWarn, Error: "unable to parse networkQuality json", Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+	}
+	dl := toMbps(*nq.DLThroughput)
+	metrics := map[string]any{"download_mbps": dl}
+	// A run that measured only the downlink leaves upload unjudged.
+	var ul *float64
+	if nq.ULThroughput != nil {
+		v := toMbps(*nq.ULThroughput)
+		ul = &v
+		metrics["upload_mbps"] = v
+	}
+	for key, v := range map[string]*float64{
+		"base_rtt_ms":  nq.BaseRTT,
+		"rpm":          nq.Responsiveness,
+		"download_rpm": nq.DLResponsiveness,
+		"upload_rpm":   nq.ULResponsiveness,
+	} {
+		if v != nil {
+			metrics[key] = *v
+		}
+	}
+	status, errMsg := planStatus(cfg, &dl, ul, metrics)
+	return model.CheckResult{ID: c.ID(), Group: c.Group(), Status: status, Metrics: metrics, Error: errMsg, Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
+}
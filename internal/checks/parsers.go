@@ -1,7 +1,10 @@
 package checks
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"math"
+	"net"
 	"regexp"
 	"sort"
 	"strconv"
@@ -138,3 +141,92 @@ func parseCurlTimings(output string) map[string]float64 {
 	}
 	return res
 }
+
+// parseNetstatDefaultRoute handles both BSD ("default") and Linux net-tools ("0.0.0.0") route tables.
+func parseNetstatDefaultRoute(output string) (gateway, iface string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case fields[0] == "default":
+			if len(fields) >= 4 {
+				iface = fields[3]
+			}
+			return fields[1], iface
+		case fields[0] == "0.0.0.0" && len(fields) >= 8:
+			return fields[1], fields[len(fields)-1]
+		}
+	}
+	return "", ""
+}
+
+type ipRoute struct {
+	Dst     string `json:"dst"`
+	Gateway string `json:"gateway"`
+	Dev     string `json:"dev"`
+}
+
+func parseIPRouteJSON(output string) (gateway, iface string) {
+	var routes []ipRoute
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &routes); err != nil {
+		return "", ""
+	}
+	for _, r := range routes {
+		if r.Dst == "default" && r.Gateway != "" {
+			return r.Gateway, r.Dev
+		}
+	}
+	return "", ""
+}
+
+type ipAddrInfo struct {
+	Family    string `json:"family"`
+	Local     string `json:"local"`
+	PrefixLen int    `json:"prefixlen"`
+}
+
+type ipInterface struct {
+	IfName    string       `json:"ifname"`
+	Flags     []string     `json:"flags"`
+	OperState string       `json:"operstate"`
+	AddrInfo  []ipAddrInfo `json:"addr_info"`
+}
+
+func (i ipInterface) isLoopback() bool {
+	for _, f := range i.Flags {
+		if f == "LOOPBACK" {
+			return true
+		}
+	}
+	return i.IfName == "lo"
+}
+
+func parseIPAddrJSON(output string) ([]ipInterface, error) {
+	var ifaces []ipInterface
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &ifaces); err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// parseProcNetRoute reads the default route from /proc/net/route, where addresses are little-endian hex.
+func parseProcNetRoute(output string) (gateway, iface string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		ip := net.IPv4(b[3], b[2], b[1], b[0])
+		if ip.IsUnspecified() {
+			continue
+		}
+		return ip.String(), fields[0]
+	}
+	return "", ""
+}
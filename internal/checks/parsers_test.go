@@ -44,3 +44,44 @@ func TestParseTracerouteSummary(t *testing.T) {
 		t.Fatalf("unexpected traceroute summary hops=%d timeout=%d", hops, timeoutHops)
 	}
 }
+
+func TestParseNetstatDefaultRoute(t *testing.T) {
+	gw, ifc := parseNetstatDefaultRoute("Routing tables\n\nInternet:\nDestination        Gateway            Flags               Netif Expire\ndefault            192.168.1.1        UGScg                 en0\n")
+	if gw != "192.168.1.1" || ifc != "en0" {
+		t.Fatalf("unexpected darwin route gw=%q iface=%q", gw, ifc)
+	}
+	gw, ifc = parseNetstatDefaultRoute("Kernel IP routing table\nDestination     Gateway         Genmask         Flags   MSS Window  irtt Iface\n0.0.0.0         10.0.0.1        0.0.0.0         UG        0 0          0 eth0\n")
+	if gw != "10.0.0.1" || ifc != "eth0" {
+		t.Fatalf("unexpected linux route gw=%q iface=%q", gw, ifc)
+	}
+}
+
+func TestParseIPRouteJSON(t *testing.T) {
+	gw, ifc := parseIPRouteJSON(`[{"dst":"default","gateway":"10.0.0.1","dev":"eth0","protocol":"dhcp","metric":100,"flags":[]}]`)
+	if gw != "10.0.0.1" || ifc != "eth0" {
+		t.Fatalf("unexpected route gw=%q iface=%q", gw, ifc)
+	}
+	if gw, _ := parseIPRouteJSON("not-json"); gw != "" {
+		t.Fatalf("expected empty gateway for invalid json, got %q", gw)
+	}
+}
+
+func TestParseProcNetRoute(t *testing.T) {
+	raw := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t0000000A\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" +
+		"eth0\t00000000\t0100000A\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"
+	gw, ifc := parseProcNetRoute(raw)
+	if gw != "10.0.0.1" || ifc != "eth0" {
+		t.Fatalf("unexpected route gw=%q iface=%q", gw, ifc)
+	}
+}
+
+func TestParseIPAddrJSON(t *testing.T) {
+	ifaces, err := parseIPAddrJSON(`[{"ifname":"lo","flags":["LOOPBACK","UP"],"operstate":"UNKNOWN","addr_info":[{"family":"inet","local":"127.0.0.1","prefixlen":8}]},{"ifname":"eth0","flags":["UP"],"operstate":"UP","addr_info":[{"family":"inet","local":"10.0.0.5","prefixlen":24}]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 2 || !ifaces[0].isLoopback() || ifaces[1].isLoopback() {
+		t.Fatalf("unexpected interfaces: %+v", ifaces)
+	}
+}
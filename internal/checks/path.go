@@ -10,15 +10,16 @@ import (
 
 type PathCheck struct{ Target string }
 
-func (c PathCheck) ID() string    { return "path." + c.Target }
-func (c PathCheck) Group() string { return "path" }
+func (c PathCheck) ID() string          { return "path." + c.Target }
+func (c PathCheck) Group() string       { return "path" }
+func (c PathCheck) DependsOn() []string { return gatewayDependency }
 
 func (c PathCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
 	if _, err := ex.LookPath("mtr"); err != nil {
 		return c.runTraceroute(ctx, ex, timeoutSec, start)
 	}
-	res := runWithTimeout(ctx, timeoutSec, ex, "mtr", "-rwzc", "10", c.Target)
+	res := runWithTimeout(ctx, timeoutSec, ex, "mtr", "-rwzc", "10", "--", c.Target)
 	if isInterruptedError(res.Err) {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
@@ -50,7 +51,7 @@ func (c PathCheck) runTraceroute(ctx context.Context, ex execx.Executor, timeout
 	if _, terr := ex.LookPath("traceroute"); terr != nil {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusSkip, Error: "mtr and traceroute not found"}
 	}
-	res := runWithTimeout(ctx, timeoutSec, ex, "traceroute", "-m", "15", c.Target)
+	res := runWithTimeout(ctx, timeoutSec, ex, "traceroute", "-m", "15", "--", c.Target)
 	if isInterruptedError(res.Err) {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
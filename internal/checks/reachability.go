@@ -12,15 +12,16 @@ import (
 
 type ReachabilityCheck struct{ Target string }
 
-func (c ReachabilityCheck) ID() string    { return "reachability." + c.Target }
-func (c ReachabilityCheck) Group() string { return "reachability" }
+func (c ReachabilityCheck) ID() string          { return "reachability." + c.Target }
+func (c ReachabilityCheck) Group() string       { return "reachability" }
+func (c ReachabilityCheck) DependsOn() []string { return gatewayDependency }
 
 func (c ReachabilityCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
 	start := time.Now()
 	if _, err := ex.LookPath("ping"); err != nil {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusSkip, Error: "ping not found"}
 	}
-	res := runWithTimeout(ctx, timeoutSec, ex, "ping", "-c", "10", c.Target)
+	res := runWithTimeout(ctx, timeoutSec, ex, "ping", pingArgs(c.Target)...)
 	if isInterruptedError(res.Err) {
 		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.Target, Status: model.StatusFail, Error: res.Err.Error(), Raw: res.Stdout, DurationMS: time.Since(start).Milliseconds()}
 	}
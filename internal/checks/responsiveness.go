@@ -0,0 +1,62 @@
+package checks
+
+import (
+	"context"
+	"net/url"
+	"netcheck/internal/config"
+	"netcheck/internal/eval"
+	"netcheck/internal/execx"
+	"netcheck/internal/model"
+	"netcheck/internal/rpm"
+	"time"
+)
+
+// ResponsivenessCheck measures Round-trips Per Minute against the
+// responsiveness endpoint whose /config document is at URL.
+type ResponsivenessCheck struct{ URL string }
+
+func (c ResponsivenessCheck) ID() string {
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+		return "responsiveness." + u.Host
+	}
+	return "responsiveness." + c.URL
+}
+func (c ResponsivenessCheck) Group() string       { return "responsiveness" }
+func (c ResponsivenessCheck) DependsOn() []string { return gatewayDependency }
+
+func (c ResponsivenessCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
+	if execx.Replaying(ex) {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusSkip, Error: notRecorded}
+	}
+	start := time.Now()
+	d := time.Duration(cfg.Responsiveness.DurationSec) * time.Second
+	// Allow for fetching /config and draining probes after the load phase.
+	t := max(time.Duration(timeoutSec)*time.Second, d+10*time.Second)
+	pctx, cancel := context.WithTimeout(ctx, t)
+	defer cancel()
+	roots, err := loadRootCAs(cfg.TLS.CABundle)
+	if err != nil {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusFail, Error: err.Error()}
+	}
+	execx.Logf(ctx, "op", "measuring responsiveness under load; %d HTTP/2 connections per direction for %s", cfg.Responsiveness.Connections, d)
+	res, err := rpm.Measure(pctx, rpm.Options{ConfigURL: c.URL, Duration: d, Connections: cfg.Responsiveness.Connections, RootCAs: roots})
+	if err != nil {
+		execx.Logf(ctx, "op", "responsiveness error: %v", err)
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusFail, Error: err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	execx.Logf(ctx, "op", "logs: rpm=%.0f self=%.1fms probes=%d/%d", res.RPM, durMS(res.SelfRTT), res.SelfProbes, res.ForeignProbes)
+	metrics := map[string]any{
+		"rpm":             res.RPM,
+		"self_rtt_ms":     durMS(res.SelfRTT),
+		"foreign_tcp_ms":  durMS(res.ForeignTCP),
+		"foreign_tls_ms":  durMS(res.ForeignTLS),
+		"foreign_http_ms": durMS(res.ForeignHTTP),
+		"self_probes":     float64(res.SelfProbes),
+		"foreign_probes":  float64(res.ForeignProbes),
+		"download_mbps":   res.DownloadMbps,
+		"upload_mbps":     res.UploadMbps,
+		"http_proto":      res.Proto,
+	}
+	status := eval.UpperIsBetter(res.RPM, cfg.Thresholds.RPMPassMin, cfg.Thresholds.RPMWarnMin)
+	return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: status, Metrics: metrics, DurationMS: time.Since(start).Milliseconds()}
+}
@@ -0,0 +1,134 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"netcheck/internal/config"
+	"netcheck/internal/eval"
+	"netcheck/internal/execx"
+	"netcheck/internal/httpx"
+	"netcheck/internal/model"
+	"os"
+	"time"
+)
+
+// TLSCheck validates the certificate chain, hostname and leaf expiry for one https URL.
+type TLSCheck struct{ URL string }
+
+func (c TLSCheck) ID() string          { return "tls." + c.hostPort() }
+func (c TLSCheck) Group() string       { return "tls" }
+func (c TLSCheck) DependsOn() []string { return gatewayDependency }
+
+func (c TLSCheck) hostPort() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return c.URL
+	}
+	if u.Port() == "" {
+		return u.Hostname()
+	}
+	return u.Host
+}
+
+func (c TLSCheck) Run(ctx context.Context, ex execx.Executor, cfg config.Config, timeoutSec int) model.CheckResult {
+	start := time.Now()
+	if !cfg.TLS.Enabled {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusSkip, Error: "tls check disabled"}
+	}
+	if execx.Replaying(ex) {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusSkip, Error: notRecorded}
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusSkip, Error: "not an https url"}
+	}
+	roots, err := loadRootCAs(cfg.TLS.CABundle)
+	if err != nil {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: c.URL, Status: model.StatusFail, Error: err.Error()}
+	}
+	host := u.Hostname()
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(host, "443")
+	}
+	t := time.Duration(timeoutSec) * time.Second
+	if t <= 0 {
+		t = 20 * time.Second
+	}
+	dctx, cancel := context.WithTimeout(ctx, t)
+	defer cancel()
+	execx.Logf(ctx, "op", "reading TLS certificate chain; handshake with %s (sni=%s)", addr, host)
+	// Verification is done below so expiry is still reported for untrusted chains.
+	d := tls.Dialer{Config: &tls.Config{ServerName: host, InsecureSkipVerify: true}}
+	conn, err := d.DialContext(dctx, "tcp", addr)
+	if err != nil {
+		execx.Logf(ctx, "op", "handshake error: %v", err)
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: addr, Status: model.StatusFail, Error: err.Error(), DurationMS: time.Since(start).Milliseconds()}
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	_ = conn.Close()
+	if len(state.PeerCertificates) == 0 {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: addr, Status: model.StatusFail, Error: "no peer certificates presented", DurationMS: time.Since(start).Milliseconds()}
+	}
+	leaf := state.PeerCertificates[0]
+	inter := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		inter.AddCert(cert)
+	}
+	_, chainErr := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: inter})
+	hostErr := leaf.VerifyHostname(host)
+	days := math.Floor(time.Until(leaf.NotAfter).Hours()/24*10) / 10
+	metrics := map[string]any{
+		"days_until_expiry": days,
+		"not_after":         leaf.NotAfter.UTC().Format(time.RFC3339),
+		"subject":           leaf.Subject.String(),
+		"issuer":            leaf.Issuer.String(),
+		"chain_len":         len(state.PeerCertificates),
+		"chain_valid":       chainErr == nil,
+		"hostname_valid":    hostErr == nil,
+		"tls_protocol":      httpx.VersionName(state.Version),
+	}
+	execx.Logf(ctx, "op", "logs: subject=%q not_after=%s chain_valid=%t hostname_valid=%t", leaf.Subject.String(), metrics["not_after"], chainErr == nil, hostErr == nil)
+	status := eval.UpperIsBetter(days, cfg.Thresholds.TLSExpiryPassDays, cfg.Thresholds.TLSExpiryWarnDays)
+	var errs []error
+	if chainErr != nil {
+		status = model.StatusFail
+		errs = append(errs, fmt.Errorf("chain: %w", chainErr))
+	}
+	if hostErr != nil {
+		status = model.StatusFail
+		errs = append(errs, fmt.Errorf("hostname: %w", hostErr))
+	}
+	if days < 0 {
+		errs = append(errs, errors.New("certificate expired"))
+	} else if status != model.StatusPass && len(errs) == 0 {
+		errs = append(errs, fmt.Errorf("certificate expires in %.1f days", days))
+	}
+	errMsg := ""
+	if err := errors.Join(errs...); err != nil {
+		errMsg = err.Error()
+	}
+	return model.CheckResult{ID: c.ID(), Group: c.Group(), Target: addr, Status: status, Metrics: metrics, Error: errMsg, DurationMS: time.Since(start).Milliseconds()}
+}
+
+// loadRootCAs returns nil (system roots) for an empty path, otherwise a pool with only the bundle.
+func loadRootCAs(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tls.ca_bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("tls.ca_bundle %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
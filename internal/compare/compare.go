@@ -3,24 +3,48 @@ package compare
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"netcheck/internal/model"
 	"os"
 	"sort"
 	"text/tabwriter"
 )
 
+// Change classifies a check across the two reports.
+type Change string
+
+const (
+	ChangeAdded     Change = "added"
+	ChangeRemoved   Change = "removed"
+	ChangeChanged   Change = "changed"
+	ChangeUnchanged Change = "unchanged"
+)
+
 type Item struct {
-	ID             string       `json:"id"`
-	BeforeStatus   model.Status `json:"before_status"`
-	AfterStatus    model.Status `json:"after_status"`
-	BeforeDuration int64        `json:"before_duration_ms"`
-	AfterDuration  int64        `json:"after_duration_ms"`
+	ID             string        `json:"id"`
+	Change         Change        `json:"change"`
+	BeforeStatus   model.Status  `json:"before_status,omitempty"`
+	AfterStatus    model.Status  `json:"after_status,omitempty"`
+	BeforeDuration int64         `json:"before_duration_ms"`
+	AfterDuration  int64         `json:"after_duration_ms"`
+	Metrics        []MetricDelta `json:"metrics,omitempty"`
+}
+
+// MetricDelta compares one numeric metric present in both reports. DeltaPct
+// is nil when the baseline is zero.
+type MetricDelta struct {
+	Name     string   `json:"name"`
+	Before   float64  `json:"before"`
+	After    float64  `json:"after"`
+	Delta    float64  `json:"delta"`
+	DeltaPct *float64 `json:"delta_pct,omitempty"`
 }
 
 type Diff struct {
-	BeforeScore int    `json:"before_score"`
-	AfterScore  int    `json:"after_score"`
-	Items       []Item `json:"items"`
+	BeforeScore int          `json:"before_score"`
+	AfterScore  int          `json:"after_score"`
+	Items       []Item       `json:"items"`
+	Regressions []Regression `json:"regressions,omitempty"`
 }
 
 func Load(path string) (model.Report, error) {
@@ -38,15 +62,69 @@ func Build(before, after model.Report) Diff {
 	for _, c := range before.Checks {
 		bm[c.ID] = c
 	}
+	seen := map[string]bool{}
 	items := make([]Item, 0, len(after.Checks))
 	for _, c := range after.Checks {
-		b := bm[c.ID]
-		items = append(items, Item{ID: c.ID, BeforeStatus: b.Status, AfterStatus: c.Status, BeforeDuration: b.DurationMS, AfterDuration: c.DurationMS})
+		seen[c.ID] = true
+		b, ok := bm[c.ID]
+		if !ok {
+			items = append(items, Item{ID: c.ID, Change: ChangeAdded, AfterStatus: c.Status, AfterDuration: c.DurationMS})
+			continue
+		}
+		it := Item{ID: c.ID, Change: ChangeUnchanged, BeforeStatus: b.Status, AfterStatus: c.Status, BeforeDuration: b.DurationMS, AfterDuration: c.DurationMS, Metrics: metricDeltas(b.Metrics, c.Metrics)}
+		if b.Status != c.Status {
+			it.Change = ChangeChanged
+		}
+		for _, m := range it.Metrics {
+			if m.Delta != 0 {
+				it.Change = ChangeChanged
+			}
+		}
+		items = append(items, it)
+	}
+	for _, c := range before.Checks {
+		if !seen[c.ID] {
+			items = append(items, Item{ID: c.ID, Change: ChangeRemoved, BeforeStatus: c.Status, BeforeDuration: c.DurationMS})
+		}
 	}
 	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
 	return Diff{BeforeScore: before.Score, AfterScore: after.Score, Items: items}
 }
 
+func metricDeltas(before, after map[string]any) []MetricDelta {
+	var out []MetricDelta
+	for name, av := range after {
+		a, ok := numeric(av)
+		if !ok {
+			continue
+		}
+		b, ok := numeric(before[name])
+		if !ok {
+			continue
+		}
+		d := MetricDelta{Name: name, Before: b, After: a, Delta: a - b}
+		if b != 0 {
+			pct := (a - b) / math.Abs(b) * 100
+			d.DeltaPct = &pct
+		}
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func numeric(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, !math.IsNaN(n) && !math.IsInf(n, 0)
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
 func WriteTable(path string, d Diff) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -54,10 +132,43 @@ func WriteTable(path string, d Diff) error {
 	}
 	defer f.Close()
 	tw := tabwriter.NewWriter(f, 0, 4, 2, ' ', 0)
-	fmt.Fprintf(tw, "ID\tBEFORE\tAFTER\tBEFORE_MS\tAFTER_MS\n")
+	fmt.Fprintf(tw, "ID\tCHANGE\tBEFORE\tAFTER\tBEFORE_MS\tAFTER_MS\n")
+	for _, it := range d.Items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\n", it.ID, it.Change, orDash(string(it.BeforeStatus)), orDash(string(it.AfterStatus)), it.BeforeDuration, it.AfterDuration)
+	}
+	fmt.Fprintf(tw, "\nScore\t\t%d\t%d\t\t\n", d.BeforeScore, d.AfterScore)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	rows := 0
 	for _, it := range d.Items {
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", it.ID, it.BeforeStatus, it.AfterStatus, it.BeforeDuration, it.AfterDuration)
+		for _, m := range it.Metrics {
+			if m.Delta == 0 {
+				continue
+			}
+			if rows == 0 {
+				fmt.Fprintf(tw, "\nID\tMETRIC\tBEFORE\tAFTER\tDELTA\tDELTA_PCT\n")
+			}
+			rows++
+			pct := "-"
+			if m.DeltaPct != nil {
+				pct = fmt.Sprintf("%+.1f%%", *m.DeltaPct)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%g\t%g\t%+g\t%s\n", it.ID, m.Name, m.Before, m.After, m.Delta, pct)
+		}
+	}
+	if len(d.Regressions) > 0 {
+		fmt.Fprintf(tw, "\nREGRESSION\tMETRIC\tBEFORE\tAFTER\tCHANGE_PCT\tMAX_PCT\n")
+		for _, r := range d.Regressions {
+			fmt.Fprintf(tw, "%s\t%s\t%g\t%g\t%s\t%g\n", r.ID, r.Metric, r.Before, r.After, r.changeText(), r.MaxPct)
+		}
 	}
-	fmt.Fprintf(tw, "\nScore\t%d\t%d\t\t\n", d.BeforeScore, d.AfterScore)
 	return tw.Flush()
 }
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -4,6 +4,7 @@ import (
 	"netcheck/internal/model"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -35,3 +36,116 @@ func TestLoadAndWriteTable(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestBuildClassifiesAndDiffsMetrics(t *testing.T) {
+	before := model.Report{Checks: []model.CheckResult{
+		{ID: "dns.a", Status: model.StatusPass, Metrics: map[string]any{"query_ms": float64(20), "rcode": "NOERROR", "min_ttl": 0}},
+		{ID: "http.a", Status: model.StatusPass, Metrics: map[string]any{"total_ms": float64(100)}},
+		{ID: "old", Status: model.StatusFail},
+	}}
+	after := model.Report{Checks: []model.CheckResult{
+		{ID: "dns.a", Status: model.StatusPass, Metrics: map[string]any{"query_ms": float64(30), "rcode": "NOERROR", "min_ttl": 60}},
+		{ID: "http.a", Status: model.StatusPass, Metrics: map[string]any{"total_ms": float64(100), "status_code": 200}},
+		{ID: "new", Status: model.StatusWarn},
+	}}
+	d := Build(before, after)
+	want := map[string]Change{"dns.a": ChangeChanged, "http.a": ChangeUnchanged, "old": ChangeRemoved, "new": ChangeAdded}
+	if len(d.Items) != len(want) {
+		t.Fatalf("unexpected items: %+v", d.Items)
+	}
+	for _, it := range d.Items {
+		if it.Change != want[it.ID] {
+			t.Fatalf("%s: expected %s, got %s", it.ID, want[it.ID], it.Change)
+		}
+	}
+	dns := d.Items[0]
+	if dns.ID != "dns.a" || len(dns.Metrics) != 2 {
+		t.Fatalf("unexpected dns deltas: %+v", dns)
+	}
+	ttl, q := dns.Metrics[0], dns.Metrics[1]
+	if ttl.Name != "min_ttl" || ttl.Delta != 60 || ttl.DeltaPct != nil {
+		t.Fatalf("unexpected min_ttl delta: %+v", ttl)
+	}
+	if q.Name != "query_ms" || q.Delta != 10 || q.DeltaPct == nil || *q.DeltaPct != 50 {
+		t.Fatalf("unexpected query_ms delta: %+v", q)
+	}
+	if http := d.Items[1]; len(http.Metrics) != 1 {
+		t.Fatalf("only shared metrics should be diffed: %+v", http.Metrics)
+	}
+}
+
+func TestEvaluateRegressionRules(t *testing.T) {
+	before := model.Report{Checks: []model.CheckResult{
+		{ID: "dns.a", Metrics: map[string]any{"query_ms": float64(20)}},
+		{ID: "bandwidth.speedtest", Metrics: map[string]any{"download_mbps": float64(100), "upload_mbps": float64(20)}},
+		{ID: "reachability.a", Metrics: map[string]any{"loss_pct": float64(0)}},
+	}}
+	after := model.Report{Checks: []model.CheckResult{
+		{ID: "dns.a", Metrics: map[string]any{"query_ms": float64(23)}},
+		{ID: "bandwidth.speedtest", Metrics: map[string]any{"download_mbps": float64(70), "upload_mbps": float64(30)}},
+		{ID: "reachability.a", Metrics: map[string]any{"loss_pct": float64(1)}},
+	}}
+	var rules []Rule
+	for _, raw := range []string{"query_ms=20", "download_mbps=10", "upload_mbps=10", "loss_pct=50%"} {
+		r, err := ParseRule(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+	d := Build(before, after)
+	regs := d.Evaluate(rules)
+	if len(regs) != 2 {
+		t.Fatalf("expected 2 regressions, got %+v", regs)
+	}
+	if regs[0].ID != "bandwidth.speedtest" || regs[0].Metric != "download_mbps" || *regs[0].ChangePct != 30 {
+		t.Fatalf("unexpected throughput regression: %+v", regs[0])
+	}
+	if regs[1].Metric != "loss_pct" || regs[1].ChangePct != nil {
+		t.Fatalf("regression from a zero baseline should have no pct: %+v", regs[1])
+	}
+	if err := CheckRules(rules, before, after); err != nil {
+		t.Fatalf("every rule matches a metric: %v", err)
+	}
+	typo, _ := ParseRule("downlod_mbps=5")
+	if err := CheckRules(append(rules, typo), before, after); err == nil || !strings.Contains(err.Error(), "downlod_mbps") {
+		t.Fatalf("expected an error for a rule matching no metric, got %v", err)
+	}
+	for _, bad := range []string{"query_ms", "=5", "query_ms=-1", "query_ms=x"} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestHigherIsBetter(t *testing.T) {
+	for metric, want := range map[string]bool{
+		"download_mbps":            true,
+		"download_pct_of_expected": true,
+		"upload_pct_of_expected":   true,
+		"rpm":                      true,
+		"download_rpm":             true,
+		"self_probes":              true,
+		"foreign_probes":           true,
+		"days_until_expiry":        true,
+		"query_ms":                 false,
+		"loss_pct":                 false,
+		"jitter_ms":                false,
+		"retransmits":              false,
+		"cpu_host_pct":             false,
+	} {
+		if got := HigherIsBetter(metric); got != want {
+			t.Fatalf("HigherIsBetter(%q) = %v, want %v", metric, got, want)
+		}
+	}
+	before := model.Report{Checks: []model.CheckResult{{ID: "bandwidth.iperf", Metrics: map[string]any{"upload_pct_of_expected": 80.0}}}}
+	after := model.Report{Checks: []model.CheckResult{{ID: "bandwidth.iperf", Metrics: map[string]any{"upload_pct_of_expected": 60.0}}}}
+	d := Build(before, after)
+	if regs := d.Evaluate([]Rule{{Metric: "upload_pct_of_expected", MaxPct: 10}}); len(regs) != 1 {
+		t.Fatalf("a drop in plan percentage is a regression, got %+v", regs)
+	}
+	d = Build(after, before)
+	if regs := d.Evaluate([]Rule{{Metric: "upload_pct_of_expected", MaxPct: 10}}); len(regs) != 0 {
+		t.Fatalf("a rise in plan percentage is an improvement, got %+v", regs)
+	}
+}
@@ -0,0 +1,124 @@
+package compare
+
+import (
+	"fmt"
+	"math"
+	"netcheck/internal/model"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Rule caps how far a metric may regress, in percent of the baseline value.
+type Rule struct {
+	Metric string
+	MaxPct float64
+}
+
+// Regression is a metric that moved in its worse direction by more than the
+// rule allows. ChangePct is nil when the baseline was zero.
+type Regression struct {
+	ID        string   `json:"id"`
+	Metric    string   `json:"metric"`
+	Before    float64  `json:"before"`
+	After     float64  `json:"after"`
+	ChangePct *float64 `json:"change_pct,omitempty"`
+	MaxPct    float64  `json:"max_pct"`
+}
+
+// ParseRule parses "metric=pct", e.g. "query_ms=20".
+func ParseRule(s string) (Rule, error) {
+	name, pct, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return Rule{}, fmt.Errorf("invalid regression rule %q: want metric=pct", s)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(pct), "%"), 64)
+	if err != nil || v < 0 {
+		return Rule{}, fmt.Errorf("invalid regression rule %q: pct must be a non-negative number", s)
+	}
+	return Rule{Metric: name, MaxPct: v}, nil
+}
+
+// Metrics that improve upwards: throughput and its share of expected_plan,
+// RPM and the probes behind it, and certificate lifetime. Everything else
+// (latency, loss, jitter, retransmits, CPU) improves downwards.
+var (
+	higherIsBetterSuffixes = []string{"_mbps", "_pct_of_expected", "rpm"}
+	higherIsBetterNames    = []string{"days_until_expiry", "self_probes", "foreign_probes"}
+)
+
+// HigherIsBetter reports whether a metric improves upwards.
+func HigherIsBetter(metric string) bool {
+	for _, s := range higherIsBetterSuffixes {
+		if strings.HasSuffix(metric, s) {
+			return true
+		}
+	}
+	return slices.Contains(higherIsBetterNames, metric)
+}
+
+// CheckRules rejects rules whose metric no check reports in any of the
+// reports, so a misspelled metric cannot make the gate pass forever.
+func CheckRules(rules []Rule, reports ...model.Report) error {
+	seen := map[string]bool{}
+	for _, r := range reports {
+		for _, c := range r.Checks {
+			for name := range c.Metrics {
+				seen[name] = true
+			}
+		}
+	}
+	var unknown []string
+	for _, r := range rules {
+		if !seen[r.Metric] && !slices.Contains(unknown, r.Metric) {
+			unknown = append(unknown, r.Metric)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("regression rule metric not found in either report: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Evaluate records every rule violation in d.Regressions and returns them.
+func (d *Diff) Evaluate(rules []Rule) []Regression {
+	d.Regressions = nil
+	for _, it := range d.Items {
+		for _, m := range it.Metrics {
+			for _, r := range rules {
+				if r.Metric != m.Name {
+					continue
+				}
+				worse := m.After - m.Before
+				if HigherIsBetter(m.Name) {
+					worse = -worse
+				}
+				if worse <= 0 {
+					continue
+				}
+				reg := Regression{ID: it.ID, Metric: m.Name, Before: m.Before, After: m.After, MaxPct: r.MaxPct}
+				if m.Before != 0 {
+					pct := worse / math.Abs(m.Before) * 100
+					if pct <= r.MaxPct {
+						continue
+					}
+					reg.ChangePct = &pct
+				}
+				d.Regressions = append(d.Regressions, reg)
+			}
+		}
+	}
+	return d.Regressions
+}
+
+func (r Regression) changeText() string {
+	if r.ChangePct == nil {
+		return "from zero"
+	}
+	return fmt.Sprintf("%.1f%%", *r.ChangePct)
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s regressed %s (%g -> %g, max %g%%)", r.ID, r.Metric, r.changeText(), r.Before, r.After, r.MaxPct)
+}
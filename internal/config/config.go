@@ -1,16 +1,30 @@
 package config
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"netcheck/internal/eval"
+	"netcheck/internal/model"
 	"os"
 	"path/filepath"
-	"strconv"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 )
 
+// SpeedtestProviders are the bandwidth.speedtest.provider values; auto picks
+// the first tool found on PATH.
+var SpeedtestProviders = []string{"auto", "speedtest-cli", "ookla", "librespeed"}
+
+// IperfDirections are the bandwidth.iperf.direction values: send measures
+// upload, reverse (-R) download and bidir (--bidir) both at once.
+var IperfDirections = []string{"send", "reverse", "bidir"}
+
+// CheckGroups are the group names checks report; per-group settings are keyed by them.
+var CheckGroups = []string{"local", "reachability", "dns", "http", "tls", "path", "bufferbloat", "bandwidth", "responsiveness"}
+
 type Config struct {
 	Targets struct {
 		Ping      []string `json:"ping"`
@@ -21,26 +35,57 @@ type Config struct {
 	Bandwidth struct {
 		Speedtest struct {
 			Enabled  bool   `json:"enabled"`
+			Provider string `json:"provider"`
 			ServerID string `json:"server_id"`
 		} `json:"speedtest"`
 		Iperf struct {
-			Enabled         bool   `json:"enabled"`
-			Target          string `json:"target"`
-			ParallelStreams int    `json:"parallel_streams"`
-			DurationSec     int    `json:"duration_sec"`
+			Enabled         bool    `json:"enabled"`
+			Target          string  `json:"target"`
+			ParallelStreams int     `json:"parallel_streams"`
+			DurationSec     int     `json:"duration_sec"`
+			Direction       string  `json:"direction"`
+			Protocol        string  `json:"protocol"`
+			BitrateMbps     float64 `json:"bitrate_mbps"`
 		} `json:"iperf"`
+		NetworkQuality struct {
+			Enabled bool `json:"enabled"`
+		} `json:"networkquality"`
 	} `json:"bandwidth"`
+	DNS struct {
+		Engine     string `json:"engine"`
+		RecordType string `json:"record_type"`
+	} `json:"dns"`
+	HTTP struct {
+		Engine     string   `json:"engine"`
+		NativeURLs []string `json:"native_urls"`
+		CurlURLs   []string `json:"curl_urls"`
+	} `json:"http"`
+	TLS struct {
+		Enabled  bool   `json:"enabled"`
+		CABundle string `json:"ca_bundle"`
+	} `json:"tls"`
+	Responsiveness struct {
+		URL         string `json:"url"`
+		DurationSec int    `json:"duration_sec"`
+		Connections int    `json:"connections"`
+	} `json:"responsiveness"`
 	ExpectedPlan struct {
 		DownloadMbps float64 `json:"download_mbps"`
 		UploadMbps   float64 `json:"upload_mbps"`
 	} `json:"expected_plan"`
-	Thresholds Thresholds `json:"thresholds"`
-	Soak       struct {
-		IntervalSec      int  `json:"interval_sec"`
-		DurationSec      int  `json:"duration_sec"`
-		EmitFinalSummary bool `json:"emit_final_summary"`
+	Thresholds  Thresholds `json:"thresholds"`
+	Concurrency struct {
+		Workers     int            `json:"workers"`
+		GroupLimits map[string]int `json:"group_limits"`
+	} `json:"concurrency"`
+	Soak struct {
+		IntervalSec      int            `json:"interval_sec"`
+		DurationSec      int            `json:"duration_sec"`
+		EmitFinalSummary bool           `json:"emit_final_summary"`
+		Schedules        map[string]int `json:"schedules"`
 	} `json:"soak"`
-	PerCheckTimeoutSec int `json:"per_check_timeout_sec"`
+	Scoring            model.ScoringModel `json:"scoring"`
+	PerCheckTimeoutSec int                `json:"per_check_timeout_sec"`
 }
 
 type Thresholds struct {
@@ -58,6 +103,10 @@ type Thresholds struct {
 	LoadedLatencyWarnDeltaMs float64 `json:"loaded_latency_warn_delta_ms"`
 	ThroughputPassPct        float64 `json:"throughput_pass_pct"`
 	ThroughputWarnPct        float64 `json:"throughput_warn_pct"`
+	TLSExpiryPassDays        float64 `json:"tls_expiry_pass_days"`
+	TLSExpiryWarnDays        float64 `json:"tls_expiry_warn_days"`
+	RPMPassMin               float64 `json:"rpm_pass_min"`
+	RPMWarnMin               float64 `json:"rpm_warn_min"`
 }
 
 func Defaults() Config {
@@ -67,13 +116,28 @@ func Defaults() Config {
 	c.Targets.Resolvers = []string{}
 	c.Targets.HTTPURLs = []string{"https://example.com"}
 	c.Bandwidth.Speedtest.Enabled = true
+	c.Bandwidth.Speedtest.Provider = "auto"
 	c.Bandwidth.Iperf.Enabled = true
 	c.Bandwidth.Iperf.ParallelStreams = 4
 	c.Bandwidth.Iperf.DurationSec = 30
+	c.Bandwidth.Iperf.Direction = "send"
+	c.Bandwidth.Iperf.Protocol = "tcp"
+	c.DNS.Engine = "native"
+	c.DNS.RecordType = "A"
+	c.HTTP.Engine = "curl"
+	c.HTTP.NativeURLs = []string{}
+	c.HTTP.CurlURLs = []string{}
+	c.TLS.Enabled = true
+	c.Responsiveness.DurationSec = 10
+	c.Responsiveness.Connections = 4
+	c.Concurrency.Workers = 4
+	c.Concurrency.GroupLimits = map[string]int{}
 	c.Soak.IntervalSec = 5
 	c.Soak.DurationSec = 0
 	c.Soak.EmitFinalSummary = true
+	c.Soak.Schedules = map[string]int{}
 	c.PerCheckTimeoutSec = 20
+	c.Scoring = eval.DefaultScoring()
 	c.Thresholds = Thresholds{
 		LossPassMax: 0.5, LossWarnMax: 2,
 		RTTP95PassMaxMs: 40, RTTP95WarnMaxMs: 80,
@@ -82,6 +146,8 @@ func Defaults() Config {
 		HTTPPassMaxMs: 800, HTTPWarnMaxMs: 2000,
 		LoadedLatencyPassDeltaMs: 30, LoadedLatencyWarnDeltaMs: 80,
 		ThroughputPassPct: 80, ThroughputWarnPct: 60,
+		TLSExpiryPassDays: 21, TLSExpiryWarnDays: 7,
+		RPMPassMin: 800, RPMWarnMin: 300,
 	}
 	return c
 }
@@ -93,6 +159,24 @@ func (c Config) AsMap() map[string]any {
 	return out
 }
 
+// HTTPEngineFor returns the engine for url: per-URL lists win over http.engine.
+func (c Config) HTTPEngineFor(url string) string {
+	for _, u := range c.HTTP.NativeURLs {
+		if u == url {
+			return "native"
+		}
+	}
+	for _, u := range c.HTTP.CurlURLs {
+		if u == url {
+			return "curl"
+		}
+	}
+	if c.HTTP.Engine == "" {
+		return "curl"
+	}
+	return c.HTTP.Engine
+}
+
 func Load(path string) (Config, error) {
 	cfg := Defaults()
 	if path == "" {
@@ -110,7 +194,7 @@ func Load(path string) (Config, error) {
 			return cfg, fmt.Errorf("invalid json config: %w", err)
 		}
 	default:
-		merged, err = parseYAMLSubset(string(b))
+		merged, _, err = parseYAML(string(b))
 		if err != nil {
 			return cfg, err
 		}
@@ -122,18 +206,248 @@ func Load(path string) (Config, error) {
 }
 
 func validate(c Config) error {
+	if issues := rangeIssues(c); len(issues) > 0 {
+		return errors.New(issues[0].Message)
+	}
+	return nil
+}
+
+// rangeIssues reports values that parse fine but are out of range or
+// inconsistent, in a stable order.
+func rangeIssues(c Config) []Issue {
+	var out []Issue
+	add := func(path, format string, args ...any) {
+		out = append(out, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
 	if len(c.Targets.Ping) == 0 {
-		return errors.New("targets.ping must not be empty")
+		add("targets.ping", "targets.ping must not be empty")
+	}
+	for _, list := range []struct {
+		path   string
+		values []string
+		check  func(string) error
+	}{
+		{"targets.ping", c.Targets.Ping, ValidateHost},
+		{"targets.dns_domains", c.Targets.DNSDomain, ValidateHost},
+		{"targets.resolvers", c.Targets.Resolvers, ValidateHost},
+		{"targets.http_urls", c.Targets.HTTPURLs, ValidateURL},
+		{"http.native_urls", c.HTTP.NativeURLs, ValidateURL},
+		{"http.curl_urls", c.HTTP.CurlURLs, ValidateURL},
+	} {
+		for i, v := range list.values {
+			if err := list.check(v); err != nil {
+				path := fmt.Sprintf("%s[%d]", list.path, i)
+				add(path, "%s: %v", path, err)
+			}
+		}
+	}
+	if t := c.Bandwidth.Iperf.Target; t != "" {
+		if err := ValidateHostPort(t); err != nil {
+			add("bandwidth.iperf.target", "bandwidth.iperf.target: %v", err)
+		}
 	}
 	if c.Bandwidth.Iperf.Enabled && c.Bandwidth.Iperf.Target != "" {
 		if strings.HasPrefix(c.Bandwidth.Iperf.Target, "127.0.0.1") || strings.HasPrefix(c.Bandwidth.Iperf.Target, "localhost") {
-			return errors.New("bandwidth.iperf.target must be remote; localhost is not allowed")
+			add("bandwidth.iperf.target", "bandwidth.iperf.target must be remote; localhost is not allowed")
 		}
 	}
-	if c.Thresholds.ThroughputWarnPct > c.Thresholds.ThroughputPassPct {
-		return errors.New("throughput_warn_pct cannot exceed throughput_pass_pct")
+	if !slices.Contains(SpeedtestProviders, c.Bandwidth.Speedtest.Provider) {
+		add("bandwidth.speedtest.provider", "bandwidth.speedtest.provider must be one of %s, got %q", strings.Join(SpeedtestProviders, ", "), c.Bandwidth.Speedtest.Provider)
 	}
-	return nil
+	// server_id ends up in the speedtest tool's argv.
+	if id := c.Bandwidth.Speedtest.ServerID; strings.Trim(id, "0123456789") != "" {
+		add("bandwidth.speedtest.server_id", "bandwidth.speedtest.server_id must contain only digits, got %q", id)
+	}
+	if u := c.Responsiveness.URL; u != "" {
+		if err := ValidateURL(u); err != nil {
+			add("responsiveness.url", "responsiveness.url: %v", err)
+		}
+	}
+	if c.Responsiveness.DurationSec < 1 {
+		add("responsiveness.duration_sec", "responsiveness.duration_sec must be at least 1")
+	}
+	if c.Responsiveness.Connections < 1 {
+		add("responsiveness.connections", "responsiveness.connections must be at least 1")
+	}
+	if c.Bandwidth.Iperf.ParallelStreams < 1 {
+		add("bandwidth.iperf.parallel_streams", "bandwidth.iperf.parallel_streams must be at least 1")
+	}
+	if c.Bandwidth.Iperf.DurationSec < 1 {
+		add("bandwidth.iperf.duration_sec", "bandwidth.iperf.duration_sec must be at least 1")
+	}
+	if !slices.Contains(IperfDirections, c.Bandwidth.Iperf.Direction) {
+		add("bandwidth.iperf.direction", "bandwidth.iperf.direction must be one of %s, got %q", strings.Join(IperfDirections, ", "), c.Bandwidth.Iperf.Direction)
+	}
+	switch c.Bandwidth.Iperf.Protocol {
+	case "tcp":
+	case "udp":
+		if c.Bandwidth.Iperf.BitrateMbps <= 0 {
+			add("bandwidth.iperf.bitrate_mbps", "bandwidth.iperf.bitrate_mbps must be set when bandwidth.iperf.protocol is udp")
+		}
+	default:
+		add("bandwidth.iperf.protocol", "bandwidth.iperf.protocol must be tcp or udp, got %q", c.Bandwidth.Iperf.Protocol)
+	}
+	switch c.DNS.Engine {
+	case "native", "dig":
+	default:
+		add("dns.engine", "dns.engine must be native or dig, got %q", c.DNS.Engine)
+	}
+	switch c.DNS.RecordType {
+	case "A", "AAAA":
+	default:
+		add("dns.record_type", "dns.record_type must be A or AAAA, got %q", c.DNS.RecordType)
+	}
+	switch c.HTTP.Engine {
+	case "curl", "native":
+	default:
+		add("http.engine", "http.engine must be curl or native, got %q", c.HTTP.Engine)
+	}
+	for _, n := range c.HTTP.NativeURLs {
+		for _, u := range c.HTTP.CurlURLs {
+			if n == u {
+				add("http.curl_urls", "url %q is listed in both http.native_urls and http.curl_urls", u)
+			}
+		}
+	}
+	if c.Concurrency.Workers < 1 {
+		add("concurrency.workers", "concurrency.workers must be at least 1")
+	}
+	groups := make([]string, 0, len(c.Concurrency.GroupLimits))
+	for g := range c.Concurrency.GroupLimits {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		if c.Concurrency.GroupLimits[g] < 0 {
+			add("concurrency.group_limits."+g, "concurrency.group_limits.%s must not be negative", g)
+		}
+	}
+	groups = groups[:0]
+	for g := range c.Soak.Schedules {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		path := "soak.schedules." + g
+		if !slices.Contains(CheckGroups, g) {
+			msg := fmt.Sprintf("%s: unknown check group %q", path, g)
+			if s := nearest(g, CheckGroups); s != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", s)
+			}
+			add(path, "%s", msg)
+			continue
+		}
+		if c.Soak.Schedules[g] < 1 {
+			add(path, "%s must be at least 1", path)
+		}
+	}
+	for _, v := range []struct {
+		path  string
+		value float64
+	}{
+		{"per_check_timeout_sec", float64(c.PerCheckTimeoutSec)},
+		{"soak.interval_sec", float64(c.Soak.IntervalSec)},
+		{"soak.duration_sec", float64(c.Soak.DurationSec)},
+		{"expected_plan.download_mbps", c.ExpectedPlan.DownloadMbps},
+		{"expected_plan.upload_mbps", c.ExpectedPlan.UploadMbps},
+		{"bandwidth.iperf.bitrate_mbps", c.Bandwidth.Iperf.BitrateMbps},
+	} {
+		if v.value < 0 {
+			add(v.path, "%s must not be negative", v.path)
+		}
+	}
+	out = append(out, scoringIssues(c.Scoring)...)
+	th := reflect.ValueOf(c.Thresholds)
+	for i := 0; i < th.NumField(); i++ {
+		if th.Field(i).Float() < 0 {
+			name := jsonName(th.Type().Field(i))
+			add("thresholds."+name, "thresholds.%s must not be negative", name)
+		}
+	}
+	t := c.Thresholds
+	for _, p := range []struct {
+		pass, warn       string
+		passMax, warnMax float64
+	}{
+		{"loss_pass_max", "loss_warn_max", t.LossPassMax, t.LossWarnMax},
+		{"rtt_p95_pass_max_ms", "rtt_p95_warn_max_ms", t.RTTP95PassMaxMs, t.RTTP95WarnMaxMs},
+		{"jitter_pass_max_ms", "jitter_warn_max_ms", t.JitterPassMaxMs, t.JitterWarnMaxMs},
+		{"dns_pass_max_ms", "dns_warn_max_ms", t.DNSPassMaxMs, t.DNSWarnMaxMs},
+		{"http_pass_max_ms", "http_warn_max_ms", t.HTTPPassMaxMs, t.HTTPWarnMaxMs},
+		{"loaded_latency_pass_delta_ms", "loaded_latency_warn_delta_ms", t.LoadedLatencyPassDeltaMs, t.LoadedLatencyWarnDeltaMs},
+	} {
+		if p.warnMax < p.passMax {
+			add("thresholds."+p.warn, "%s cannot be lower than %s", p.warn, p.pass)
+		}
+	}
+	if t.ThroughputWarnPct > t.ThroughputPassPct {
+		add("thresholds.throughput_warn_pct", "throughput_warn_pct cannot exceed throughput_pass_pct")
+	}
+	if t.TLSExpiryWarnDays > t.TLSExpiryPassDays {
+		add("thresholds.tls_expiry_warn_days", "tls_expiry_warn_days cannot exceed tls_expiry_pass_days")
+	}
+	if t.RPMWarnMin > t.RPMPassMin {
+		add("thresholds.rpm_warn_min", "rpm_warn_min cannot exceed rpm_pass_min")
+	}
+	return out
+}
+
+func scoringIssues(m model.ScoringModel) []Issue {
+	var out []Issue
+	add := func(path, format string, args ...any) {
+		out = append(out, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	positive := false
+	for _, cat := range sortedMapKeys(m.Weights) {
+		switch w := m.Weights[cat]; {
+		case w < 0:
+			add("scoring.weights."+cat, "scoring.weights.%s must not be negative", cat)
+		case w > 0:
+			positive = true
+		}
+	}
+	if !positive {
+		add("scoring.weights", "scoring.weights must give at least one category a positive weight")
+	}
+	for _, g := range sortedMapKeys(m.Groups) {
+		if cat := m.Groups[g]; cat != "" {
+			if _, ok := m.Weights[cat]; !ok {
+				add("scoring.groups."+g, "scoring.groups.%s: unknown category %q", g, cat)
+			}
+		}
+	}
+	if cat := m.DefaultCategory; cat != "" {
+		if _, ok := m.Weights[cat]; !ok {
+			add("scoring.default_category", "scoring.default_category: unknown category %q", cat)
+		}
+	}
+	for _, st := range sortedMapKeys(m.StatusValues) {
+		path := "scoring.status_values." + string(st)
+		switch st {
+		case model.StatusPass, model.StatusWarn, model.StatusFail, model.StatusSkip:
+		default:
+			add(path, "scoring.status_values: unknown status %q", st)
+			continue
+		}
+		if v := m.StatusValues[st]; v < 0 || v > 1 {
+			add(path, "%s must be between 0 and 1", path)
+		}
+	}
+	for _, id := range sortedMapKeys(m.CheckWeights) {
+		if m.CheckWeights[id] < 0 {
+			add("scoring.check_weights."+id, "scoring.check_weights.%s must not be negative", id)
+		}
+	}
+	return out
+}
+
+func sortedMapKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
 }
 
 func applyMap(cfg *Config, m map[string]any) error {
@@ -160,110 +474,33 @@ func deepMerge(dst map[string]any, src map[string]any) {
 	}
 }
 
-// parseYAMLSubset parses a minimal YAML subset: nested maps via indentation and lists with "- " entries.
-func parseYAMLSubset(src string) (map[string]any, error) {
-	root := map[string]any{}
-	type frame struct {
-		indent int
-		key    string
-		obj    map[string]any
-	}
-	stack := []frame{{indent: -1, obj: root}}
-	sc := bufio.NewScanner(strings.NewReader(src))
-	for sc.Scan() {
-		line := sc.Text()
-		trim := strings.TrimSpace(line)
-		if trim == "" || strings.HasPrefix(trim, "#") {
-			continue
-		}
-		indent := len(line) - len(strings.TrimLeft(line, " "))
-		for len(stack) > 1 && indent <= stack[len(stack)-1].indent {
-			stack = stack[:len(stack)-1]
-		}
-		parent := stack[len(stack)-1].obj
-		if strings.HasPrefix(trim, "- ") {
-			return nil, fmt.Errorf("invalid yaml: list item without key context: %s", line)
-		}
-		parts := strings.SplitN(trim, ":", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid yaml line: %s", line)
-		}
-		key := strings.TrimSpace(parts[0])
-		val := strings.TrimSpace(parts[1])
-		val = strings.TrimSpace(stripInlineComment(val))
-		if val == "" {
-			next := map[string]any{}
-			parent[key] = next
-			stack = append(stack, frame{indent: indent, key: key, obj: next})
-			continue
+// Limits returns the pass thresholds the checks of group are judged against,
+// used to explain where a check lost score.
+func (t Thresholds) Limits(group string) []eval.Limit {
+	switch group {
+	case "local":
+		return []eval.Limit{{Metric: "loss_pct", Pass: t.LossPassMax, LowerIsBetter: true}}
+	case "reachability":
+		return []eval.Limit{
+			{Metric: "loss_pct", Pass: t.LossPassMax, LowerIsBetter: true},
+			{Metric: "rtt_p95_ms", Pass: t.RTTP95PassMaxMs, LowerIsBetter: true},
+			{Metric: "jitter_ms", Pass: t.JitterPassMaxMs, LowerIsBetter: true},
 		}
-		if strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]") {
-			items := strings.Split(strings.TrimSuffix(strings.TrimPrefix(val, "["), "]"), ",")
-			arr := make([]any, 0, len(items))
-			for _, it := range items {
-				t := stripInlineComment(strings.TrimSpace(it))
-				t = strings.Trim(strings.TrimSpace(t), `"'`)
-				if t == "" {
-					continue
-				}
-				arr = append(arr, t)
-			}
-			parent[key] = arr
-			continue
-		}
-		if v, err := parseScalar(val); err == nil {
-			parent[key] = v
-		} else {
-			parent[key] = strings.Trim(val, `"'`)
-		}
-	}
-	if err := sc.Err(); err != nil {
-		return nil, err
-	}
-	return root, nil
-}
-
-func parseScalar(v string) (any, error) {
-	s := strings.Trim(v, `"'`)
-	if s == "true" {
-		return true, nil
-	}
-	if s == "false" {
-		return false, nil
-	}
-	if i, err := strconv.Atoi(s); err == nil {
-		return i, nil
-	}
-	if f, err := strconv.ParseFloat(s, 64); err == nil {
-		return f, nil
-	}
-	if s != "" {
-		return s, nil
-	}
-	return nil, errors.New("empty scalar")
-}
-
-func stripInlineComment(s string) string {
-	var b strings.Builder
-	inSingle := false
-	inDouble := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		switch ch {
-		case '\'':
-			if !inDouble {
-				inSingle = !inSingle
-			}
-		case '"':
-			if !inSingle {
-				inDouble = !inDouble
-			}
-		case '#':
-			if !inSingle && !inDouble {
-				return strings.TrimSpace(b.String())
-			}
+	case "dns":
+		return []eval.Limit{{Metric: "query_ms", Pass: t.DNSPassMaxMs, LowerIsBetter: true}}
+	case "http":
+		return []eval.Limit{{Metric: "total_ms", Pass: t.HTTPPassMaxMs, LowerIsBetter: true}}
+	case "bufferbloat":
+		return []eval.Limit{{Metric: "delta_ms", Pass: t.LoadedLatencyPassDeltaMs, LowerIsBetter: true}}
+	case "bandwidth":
+		return []eval.Limit{
+			{Metric: "download_pct_of_expected", Pass: t.ThroughputPassPct},
+			{Metric: "upload_pct_of_expected", Pass: t.ThroughputPassPct},
 		}
-		b.WriteByte(ch)
+	case "tls":
+		return []eval.Limit{{Metric: "days_until_expiry", Pass: t.TLSExpiryPassDays}}
+	case "responsiveness":
+		return []eval.Limit{{Metric: "rpm", Pass: t.RPMPassMin}}
 	}
-	return strings.TrimSpace(b.String())
+	return nil
 }
@@ -98,3 +98,266 @@ targets:
 		t.Fatalf("unexpected resolvers: %+v", cfg.Targets.Resolvers)
 	}
 }
+
+func TestHTTPEngineForPerURLOverrides(t *testing.T) {
+	c := Defaults()
+	c.HTTP.NativeURLs = []string{"https://a.example"}
+	if got := c.HTTPEngineFor("https://a.example"); got != "native" {
+		t.Fatalf("expected native override, got %s", got)
+	}
+	if got := c.HTTPEngineFor("https://b.example"); got != "curl" {
+		t.Fatalf("expected default curl, got %s", got)
+	}
+	c.HTTP.Engine = "native"
+	c.HTTP.CurlURLs = []string{"https://b.example"}
+	if got := c.HTTPEngineFor("https://b.example"); got != "curl" {
+		t.Fatalf("expected curl override, got %s", got)
+	}
+}
+
+func TestLoadYAMLBlockSequencesAndAnchors(t *testing.T) {
+	d := t.TempDir()
+	p := filepath.Join(d, "netcheck.yaml")
+	content := `
+resolvers: &resolvers
+  - "1.1.1.1" # primary
+  - 9.9.9.9
+targets:
+  ping:
+  - 8.8.8.8
+  resolvers: *resolvers
+  http_urls:
+    - "https://example.com/?a=1,b=2#frag"
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Targets.Ping) != 1 || cfg.Targets.Ping[0] != "8.8.8.8" {
+		t.Fatalf("unexpected ping targets: %+v", cfg.Targets.Ping)
+	}
+	if len(cfg.Targets.Resolvers) != 2 || cfg.Targets.Resolvers[1] != "9.9.9.9" {
+		t.Fatalf("unexpected resolvers: %+v", cfg.Targets.Resolvers)
+	}
+	if cfg.Targets.HTTPURLs[0] != "https://example.com/?a=1,b=2#frag" {
+		t.Fatalf("unexpected url: %q", cfg.Targets.HTTPURLs[0])
+	}
+}
+
+func TestLoadYAMLReportsLineAndColumn(t *testing.T) {
+	d := t.TempDir()
+	p := filepath.Join(d, "netcheck.yaml")
+	if err := os.WriteFile(p, []byte("targets:\n  ping: [\"1.1.1.1\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(p)
+	if err == nil || err.Error() != "yaml: line 2, column 9: unterminated flow collection" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateReportsUnknownKeysTypesAndRanges(t *testing.T) {
+	d := t.TempDir()
+	p := filepath.Join(d, "netcheck.yaml")
+	content := `targets:
+  ping: [1.1.1.1]
+per_check_timout_sec: 5
+thresholds:
+  dns_pass_ms: 30
+  http_warn_max_ms: 100
+bandwidth:
+  iperf:
+    parallel_streams: "4"
+soak:
+  interval_sec: -1
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{
+		{Path: "per_check_timout_sec", Line: 3, Message: "unknown key per_check_timout_sec (did you mean per_check_timeout_sec?)"},
+		{Path: "thresholds.dns_pass_ms", Line: 5, Message: "unknown key thresholds.dns_pass_ms (did you mean thresholds.dns_pass_max_ms?)"},
+		{Path: "thresholds.http_warn_max_ms", Line: 6, Message: "http_warn_max_ms cannot be lower than http_pass_max_ms"},
+		{Path: "bandwidth.iperf.parallel_streams", Line: 9, Message: `bandwidth.iperf.parallel_streams: expected int, got string "4"`},
+		{Path: "soak.interval_sec", Line: 11, Message: "soak.interval_sec must not be negative"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, want[i], issues[i])
+		}
+	}
+}
+
+func TestValidateCleanConfig(t *testing.T) {
+	d := t.TempDir()
+	p := filepath.Join(d, "netcheck.json")
+	if err := os.WriteFile(p, []byte(`{"targets":{"ping":["9.9.9.9"]},"bandwidth":{"iperf":{"duration_sec":10}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestLoadRejectsWarnBelowPassForLowerIsBetter(t *testing.T) {
+	c := Defaults()
+	c.Thresholds.DNSWarnMaxMs = 10
+	err := validate(c)
+	if err == nil || err.Error() != "dns_warn_max_ms cannot be lower than dns_pass_max_ms" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateSoakSchedules(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	content := `soak:
+  schedules:
+    reachability: 5
+    dnss: 30
+    bandwidth: 0
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{
+		{Path: "soak.schedules.dnss", Line: 4, Message: `soak.schedules.dnss: unknown check group "dnss" (did you mean dns?)`},
+		{Path: "soak.schedules.bandwidth", Line: 5, Message: "soak.schedules.bandwidth must be at least 1"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, want[i], issues[i])
+		}
+	}
+}
+
+func TestValidateScoring(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	content := `scoring:
+  weights:
+    voice: 10
+    dns: -1
+  groups:
+    responsiveness: voice
+    tls: web
+  default_category: voice
+  status_values:
+    skip: 1.5
+  check_weights:
+    http.https://example.com: 2
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{
+		{Path: "scoring.weights.dns", Line: 4, Message: "scoring.weights.dns must not be negative"},
+		{Path: "scoring.groups.tls", Line: 7, Message: `scoring.groups.tls: unknown category "web"`},
+		{Path: "scoring.status_values.skip", Line: 10, Message: "scoring.status_values.skip must be between 0 and 1"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, want[i], issues[i])
+		}
+	}
+	if d := Defaults().Scoring; d.Weights["reliability"] != 35 || d.Groups["bandwidth"] != "throughput" {
+		t.Fatalf("defaults should carry the built-in model: %+v", d)
+	}
+}
+
+func TestValidateResponsiveness(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	content := `responsiveness:
+  url: "ftp://rpm.example/config"
+  connections: 0
+thresholds:
+  rpm_warn_min: 900
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{
+		{Path: "responsiveness.url", Line: 2, Message: `responsiveness.url: "ftp://rpm.example/config" must use http or https`},
+		{Path: "responsiveness.connections", Line: 3, Message: "responsiveness.connections must be at least 1"},
+		{Path: "thresholds.rpm_warn_min", Line: 5, Message: "rpm_warn_min cannot exceed rpm_pass_min"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, want[i], issues[i])
+		}
+	}
+}
+
+func TestLoadRejectsUnknownSpeedtestProvider(t *testing.T) {
+	c := Defaults()
+	if c.Bandwidth.Speedtest.Provider != "auto" {
+		t.Fatalf("expected auto provider by default, got %q", c.Bandwidth.Speedtest.Provider)
+	}
+	c.Bandwidth.Speedtest.Provider = "fast.com"
+	err := validate(c)
+	if err == nil || err.Error() != `bandwidth.speedtest.provider must be one of auto, speedtest-cli, ookla, librespeed, got "fast.com"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateIperfModes(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "netcheck.yaml")
+	content := `bandwidth:
+  iperf:
+    target: "10.0.0.2"
+    direction: "upload"
+    protocol: "udp"
+`
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Validate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{
+		{Path: "bandwidth.iperf.bitrate_mbps", Line: 2, Message: "bandwidth.iperf.bitrate_mbps must be set when bandwidth.iperf.protocol is udp"},
+		{Path: "bandwidth.iperf.direction", Line: 4, Message: `bandwidth.iperf.direction must be one of send, reverse, bidir, got "upload"`},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, want[i], issues[i])
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Targets end up in argv of ping, dig, mtr, curl and iperf3. Anything that is
+// not a plain hostname, IP literal or http(s) URL is rejected here so that a
+// value like "-oProxyCommand=..." can never be read as an option.
+
+// ValidateHost accepts a DNS hostname or an IP literal without zone.
+func ValidateHost(s string) error {
+	if s == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	if strings.HasPrefix(s, "-") {
+		return fmt.Errorf("%q must not start with '-'", s)
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		if addr.Zone() != "" {
+			return fmt.Errorf("%q: IPv6 zones are not supported", s)
+		}
+		return nil
+	}
+	name := strings.TrimSuffix(s, ".")
+	if len(name) == 0 || len(name) > 253 {
+		return fmt.Errorf("%q is not a valid hostname", s)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !validLabel(label) {
+			return fmt.Errorf("%q is not a valid hostname or IP address", s)
+		}
+	}
+	return nil
+}
+
+func validLabel(l string) bool {
+	if len(l) == 0 || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+		return false
+	}
+	for _, r := range l {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateHostPort accepts a host with an optional port: "host", "host:port",
+// "[v6]:port" or a bare IPv6 literal.
+func ValidateHostPort(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return ValidateHost(s)
+	}
+	if err := ValidateHost(host); err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q: port must be 1-65535", s)
+	}
+	return nil
+}
+
+// ValidateURL accepts absolute http and https URLs with a valid host.
+func ValidateURL(s string) error {
+	if strings.HasPrefix(s, "-") {
+		return fmt.Errorf("%q must not start with '-'", s)
+	}
+	if strings.ContainsFunc(s, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return fmt.Errorf("%q must not contain spaces or control characters", s)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid URL", s)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must use http or https", s)
+	}
+	if err := ValidateHost(u.Hostname()); err != nil {
+		return fmt.Errorf("%q: host %v", s, err)
+	}
+	if p := u.Port(); p != "" {
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("%q: port must be 1-65535", s)
+		}
+	}
+	return nil
+}
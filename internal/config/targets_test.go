@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTargetValidation(t *testing.T) {
+	cases := []struct {
+		name  string
+		check func(string) error
+		in    string
+		ok    bool
+	}{
+		{"hostname", ValidateHost, "dns.google", true},
+		{"fqdn", ValidateHost, "example.com.", true},
+		{"ipv4", ValidateHost, "1.1.1.1", true},
+		{"ipv6", ValidateHost, "2606:4700:4700::1111", true},
+		{"underscore label", ValidateHost, "_dmarc.example.com", true},
+		{"empty", ValidateHost, "", false},
+		{"ssh option", ValidateHost, "-oProxyCommand=sh", false},
+		{"long option", ValidateHost, "--output=/etc/x", false},
+		{"dig option", ValidateHost, "+short", false},
+		{"dig server", ValidateHost, "@evil.example", false},
+		{"space", ValidateHost, "example.com -f", false},
+		{"newline", ValidateHost, "example.com\n-f", false},
+		{"shell", ValidateHost, "$(id)", false},
+		{"path", ValidateHost, "../../etc/passwd", false},
+		{"label hyphen", ValidateHost, "-a.example.com", false},
+		{"empty label", ValidateHost, "a..b", false},
+		{"long label", ValidateHost, strings.Repeat("a", 64) + ".com", false},
+		{"ipv6 zone", ValidateHost, "fe80::1%eth0", false},
+
+		{"host port", ValidateHostPort, "iperf.example.com:5201", true},
+		{"bracketed v6 port", ValidateHostPort, "[2001:db8::1]:5201", true},
+		{"bare host", ValidateHostPort, "10.0.0.2", true},
+		{"bad port", ValidateHostPort, "iperf.example.com:0", false},
+		{"port option", ValidateHostPort, "host:-p", false},
+		{"option host", ValidateHostPort, "-R:5201", false},
+
+		{"https", ValidateURL, "https://example.com/path?q=1", true},
+		{"http port", ValidateURL, "http://10.0.0.1:8080/", true},
+		{"curl output", ValidateURL, "--output=/etc/x", false},
+		{"curl config", ValidateURL, "-K/etc/passwd", false},
+		{"file scheme", ValidateURL, "file:///etc/passwd", false},
+		{"no scheme", ValidateURL, "example.com", false},
+		{"space", ValidateURL, "https://example.com -o /tmp/x", false},
+		{"bad host", ValidateURL, "https://-oProxyCommand=x/", false},
+		{"bad port", ValidateURL, "https://example.com:99999/", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.check(tc.in)
+			if tc.ok && err != nil {
+				t.Fatalf("%q: unexpected error %v", tc.in, err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("%q: expected rejection", tc.in)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsInjectedTargets(t *testing.T) {
+	c := Defaults()
+	c.Targets.Ping = []string{"1.1.1.1", "-oProxyCommand=sh"}
+	c.Targets.HTTPURLs = []string{"--output=/etc/x"}
+	c.Bandwidth.Iperf.Target = "-R"
+	c.Bandwidth.Speedtest.ServerID = "1234 --secure"
+	issues := rangeIssues(c)
+	want := map[string]bool{"targets.ping[1]": true, "targets.http_urls[0]": true, "bandwidth.iperf.target": true, "bandwidth.speedtest.server_id": true}
+	for _, is := range issues {
+		delete(want, is.Path)
+	}
+	if len(want) != 0 {
+		t.Fatalf("missing issues for %v in %+v", want, issues)
+	}
+	if err := validate(c); err == nil || !strings.Contains(err.Error(), "targets.ping[1]") {
+		t.Fatalf("expected load to fail on the ping target, got %v", err)
+	}
+}
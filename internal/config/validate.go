@@ -0,0 +1,244 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Issue is one problem found by Validate. Line is 0 when the source position
+// is unknown (JSON files, or values that come from defaults).
+type Issue struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validate checks a config file strictly: unknown keys, type mismatches and
+// out-of-range values are all collected instead of stopping at the first.
+// The error is non-nil only when the file cannot be read or parsed.
+func Validate(path string) ([]Issue, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	lines := map[string]int{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("invalid json config: %w", err)
+		}
+	} else if m, lines, err = parseYAML(string(b)); err != nil {
+		return nil, err
+	}
+	issues := schemaIssues(reflect.TypeOf(Config{}), m, "")
+	// json.Unmarshal keeps decoding past type errors, so range checks still
+	// see every well-typed value; mismatches are already reported above.
+	cfg := Defaults()
+	if err := applyMap(&cfg, m); err != nil && len(issues) == 0 {
+		issues = append(issues, Issue{Message: err.Error()})
+	}
+	issues = append(issues, rangeIssues(cfg)...)
+	for i := range issues {
+		issues[i].Line = lineFor(lines, issues[i].Path)
+	}
+	sort.SliceStable(issues, func(a, b int) bool {
+		if issues[a].Line != issues[b].Line {
+			return issues[a].Line < issues[b].Line
+		}
+		return issues[a].Path < issues[b].Path
+	})
+	return issues, nil
+}
+
+// lineFor returns the line of path or of its closest recorded ancestor.
+func lineFor(lines map[string]int, path string) int {
+	for path != "" {
+		if l, ok := lines[path]; ok {
+			return l
+		}
+		cut := strings.LastIndexAny(path, ".[")
+		if cut < 0 {
+			break
+		}
+		path = path[:cut]
+	}
+	return 0
+}
+
+// schemaIssues walks a decoded document against the Go type it will be
+// unmarshalled into.
+func schemaIssues(t reflect.Type, v any, path string) []Issue {
+	if v == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return []Issue{mismatch(path, "mapping", v)}
+		}
+		fields := map[string]reflect.Type{}
+		names := make([]string, 0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			name := jsonName(t.Field(i))
+			fields[name] = t.Field(i).Type
+			names = append(names, name)
+		}
+		var out []Issue
+		for _, k := range sortedKeys(m) {
+			child := joinPath(path, k)
+			ft, ok := fields[k]
+			if !ok {
+				msg := "unknown key " + child
+				if s := nearest(k, names); s != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", joinPath(path, s))
+				}
+				out = append(out, Issue{Path: child, Message: msg})
+				continue
+			}
+			out = append(out, schemaIssues(ft, m[k], child)...)
+		}
+		return out
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return []Issue{mismatch(path, "mapping", v)}
+		}
+		var out []Issue
+		for _, k := range sortedKeys(m) {
+			out = append(out, schemaIssues(t.Elem(), m[k], joinPath(path, k))...)
+		}
+		return out
+	case reflect.Slice:
+		list, ok := v.([]any)
+		if !ok {
+			return []Issue{mismatch(path, "list", v)}
+		}
+		var out []Issue
+		for i, item := range list {
+			out = append(out, schemaIssues(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return out
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return []Issue{mismatch(path, "string", v)}
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return []Issue{mismatch(path, "bool", v)}
+		}
+	case reflect.Int:
+		switch n := v.(type) {
+		case int:
+		case float64:
+			if n != math.Trunc(n) {
+				return []Issue{mismatch(path, "int", v)}
+			}
+		default:
+			return []Issue{mismatch(path, "int", v)}
+		}
+	case reflect.Float64:
+		switch n := v.(type) {
+		case int:
+		case float64:
+			if math.IsInf(n, 0) || math.IsNaN(n) {
+				return []Issue{{Path: path, Message: fmt.Sprintf("%s must be a finite number", path)}}
+			}
+		default:
+			return []Issue{mismatch(path, "number", v)}
+		}
+	}
+	return nil
+}
+
+func mismatch(path, want string, got any) Issue {
+	kind := "string"
+	switch got.(type) {
+	case map[string]any:
+		kind = "mapping"
+	case []any:
+		kind = "list"
+	case bool:
+		kind = "bool"
+	case int:
+		kind = "int"
+	case float64:
+		kind = "number"
+	}
+	return Issue{Path: path, Message: fmt.Sprintf("%s: expected %s, got %s %s", path, want, kind, describeValue(got))}
+}
+
+func describeValue(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "{...}"
+	case []any:
+		return "[...]"
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprint(v)
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nearest returns the candidate closest to key by edit distance, or "" when
+// nothing is close enough to be a plausible typo.
+func nearest(key string, candidates []string) string {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := editDistance(key, c)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	limit := len(key) / 2
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDist < 0 || bestDist > limit {
+		return ""
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,966 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// YAMLError reports a parse failure at a 1-based source position.
+type YAMLError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *YAMLError) Error() string {
+	return fmt.Sprintf("yaml: line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+var (
+	yamlIntRe   = regexp.MustCompile(`^[-+]?[0-9][0-9_]*$`)
+	yamlFloatRe = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9][0-9_]*(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// yamlParser is a block/flow YAML reader covering what hand-written configs use:
+// nested mappings, block and flow sequences, lists of maps, anchors, aliases and
+// merge keys, quoted and block scalars. Values decode to map[string]any, []any,
+// string, int, float64, bool or nil.
+type yamlParser struct {
+	src     string
+	pos     int
+	anchors map[string]any
+	// path is the dotted location of the node being parsed; lines maps each
+	// mapping key and sequence entry path to its source line.
+	path  string
+	lines map[string]int
+}
+
+// parseYAML decodes a config document and reports the source line of every
+// key path, e.g. "thresholds.dns_pass_max_ms" or "targets.ping[1]".
+func parseYAML(src string) (map[string]any, map[string]int, error) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.TrimPrefix(src, "\ufeff")
+	p := &yamlParser{src: src, anchors: map[string]any{}, lines: map[string]int{}}
+	root, err := p.parseDocument()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch v := root.(type) {
+	case nil:
+		return map[string]any{}, p.lines, nil
+	case map[string]any:
+		return v, p.lines, nil
+	default:
+		return nil, nil, &YAMLError{Line: 1, Column: 1, Msg: "config root must be a mapping"}
+	}
+}
+
+func (p *yamlParser) parseDocument() (any, error) {
+	indent, ok, err := p.nextContent()
+	if err != nil || !ok {
+		return nil, err
+	}
+	if indent == 0 && p.atMarker("---") {
+		p.pos += 3
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+	}
+	root, err := p.parseBlockNode(-1, false)
+	if err != nil {
+		return nil, err
+	}
+	indent, ok, err = p.nextContent()
+	if err != nil || !ok {
+		return root, err
+	}
+	switch {
+	case indent == 0 && p.atMarker("..."):
+		return root, nil
+	case indent == 0 && p.atMarker("---"):
+		return nil, p.errorf(p.pos, "multiple documents are not supported")
+	default:
+		return nil, p.errorf(p.pos, "unexpected content")
+	}
+}
+
+// parseBlockNode parses the node on the next content line if it is indented
+// deeper than parentIndent. A sequence may sit at the parent's indentation when
+// it is a mapping value ("key:\n- a").
+func (p *yamlParser) parseBlockNode(parentIndent int, allowSameIndentSeq bool) (any, error) {
+	indent, ok, err := p.nextContent()
+	if err != nil || !ok {
+		return nil, err
+	}
+	if indent <= parentIndent {
+		if allowSameIndentSeq && indent == parentIndent && p.isSeqEntry() {
+			return p.parseSequence(indent)
+		}
+		return nil, nil
+	}
+	switch {
+	case p.isSeqEntry():
+		return p.parseSequence(indent)
+	case p.isKeyLine():
+		return p.parseMapping(indent)
+	}
+	v, err := p.parseValue(parentIndent, false)
+	return v, err
+}
+
+func (p *yamlParser) parseSequence(indent int) (any, error) {
+	out := []any{}
+	for {
+		leave := p.enter(fmt.Sprintf("%s[%d]", p.path, len(out)), p.pos)
+		p.pos++ // '-'
+		item, err := p.parseValue(indent, true)
+		leave()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+		next, ok, err := p.nextContent()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || next < indent || p.atDocumentEnd(next) {
+			return out, nil
+		}
+		if next > indent {
+			return nil, p.errorf(p.pos, "unexpected indentation")
+		}
+		if !p.isSeqEntry() {
+			return out, nil
+		}
+	}
+}
+
+func (p *yamlParser) parseMapping(indent int) (any, error) {
+	out := map[string]any{}
+	explicit := map[string]bool{}
+	for {
+		keyPos := p.pos
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		leave := p.enter(joinPath(p.path, key), keyPos)
+		v, err := p.parseValue(indent, false)
+		leave()
+		if err != nil {
+			return nil, err
+		}
+		if key == "<<" {
+			if err := p.merge(out, explicit, v, keyPos); err != nil {
+				return nil, err
+			}
+		} else {
+			if explicit[key] {
+				return nil, p.errorf(keyPos, "duplicate key %q", key)
+			}
+			explicit[key] = true
+			out[key] = v
+		}
+		next, ok, err := p.nextContent()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || next < indent || p.atDocumentEnd(next) {
+			return out, nil
+		}
+		if next > indent {
+			return nil, p.errorf(p.pos, "unexpected indentation")
+		}
+		if !p.isKeyLine() {
+			if p.isSeqEntry() {
+				return nil, p.errorf(p.pos, "sequence entry is not allowed inside a mapping")
+			}
+			return nil, p.errorf(p.pos, "expected a mapping key")
+		}
+	}
+}
+
+// merge applies a "<<" merge key; keys set explicitly in the mapping win.
+func (p *yamlParser) merge(dst map[string]any, explicit map[string]bool, v any, pos int) error {
+	sources := []any{v}
+	if list, ok := v.([]any); ok {
+		sources = list
+	}
+	for _, src := range sources {
+		m, ok := src.(map[string]any)
+		if !ok {
+			return p.errorf(pos, "merge key value must be a mapping or a list of mappings")
+		}
+		for k, mv := range m {
+			if !explicit[k] {
+				if _, set := dst[k]; !set {
+					dst[k] = mv
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseValue parses what follows "key:" or "- " on the current line, descending
+// into a nested block when the line ends there.
+func (p *yamlParser) parseValue(parentIndent int, seqItem bool) (any, error) {
+	p.skipSpaces()
+	anchor, forceStr, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	switch {
+	case p.atLineEnd():
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+		v, err = p.parseBlockNode(parentIndent, !seqItem)
+	case seqItem && p.isSeqEntry():
+		v, err = p.parseSequence(p.column())
+	case seqItem && p.isKeyLine():
+		v, err = p.parseMapping(p.column())
+	default:
+		v, err = p.parseInline(parentIndent, forceStr)
+		if err == nil {
+			err = p.endLine()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = v
+	}
+	return v, nil
+}
+
+func (p *yamlParser) parseProperties() (anchor string, forceStr bool, err error) {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '&':
+			start := p.pos
+			p.pos++
+			anchor = p.readName()
+			if anchor == "" {
+				return "", false, p.errorf(start, "empty anchor name")
+			}
+		case '!':
+			tag := p.readToken()
+			forceStr = tag == "!!str" || tag == "!str"
+		default:
+			return anchor, forceStr, nil
+		}
+		p.skipSpaces()
+	}
+	return anchor, forceStr, nil
+}
+
+func (p *yamlParser) parseInline(parentIndent int, forceStr bool) (any, error) {
+	switch p.src[p.pos] {
+	case '*':
+		return p.parseAlias()
+	case '"':
+		return p.parseDoubleQuoted()
+	case '\'':
+		return p.parseSingleQuoted()
+	case '[', '{':
+		return p.parseFlow()
+	case '|', '>':
+		return p.parseBlockScalar(parentIndent)
+	default:
+		return p.parsePlain(parentIndent, forceStr)
+	}
+}
+
+func (p *yamlParser) parseAlias() (any, error) {
+	start := p.pos
+	p.pos++
+	name := p.readName()
+	v, ok := p.anchors[name]
+	if !ok {
+		return nil, p.errorf(start, "unknown anchor %q", name)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) parseKey() (string, error) {
+	start := p.pos
+	var key string
+	switch p.src[p.pos] {
+	case '"':
+		v, err := p.parseDoubleQuoted()
+		if err != nil {
+			return "", err
+		}
+		key = v.(string)
+	case '\'':
+		v, err := p.parseSingleQuoted()
+		if err != nil {
+			return "", err
+		}
+		key = v.(string)
+	default:
+		end, _ := p.keyColon()
+		key = strings.TrimSpace(p.src[p.pos:end])
+		p.pos = end
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+		return "", p.errorf(start, "expected ':' after mapping key")
+	}
+	p.pos++
+	return key, nil
+}
+
+func (p *yamlParser) parseDoubleQuoted() (any, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\n':
+			p.foldLineBreak(&b)
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return nil, p.errorf(start, "unterminated double-quoted string")
+			}
+			esc := p.src[p.pos+1]
+			p.pos += 2
+			switch esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case '"', '\\', '/', ' ':
+				b.WriteByte(esc)
+			case '\n':
+				for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+					p.pos++
+				}
+			case 'x', 'u', 'U':
+				n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[esc]
+				if p.pos+n > len(p.src) {
+					return nil, p.errorf(p.pos-2, "invalid escape sequence")
+				}
+				r, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+				if err != nil {
+					return nil, p.errorf(p.pos-2, "invalid escape sequence")
+				}
+				b.WriteRune(rune(r))
+				p.pos += n
+			default:
+				return nil, p.errorf(p.pos-2, "invalid escape sequence \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, p.errorf(start, "unterminated double-quoted string")
+}
+
+func (p *yamlParser) parseSingleQuoted() (any, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\'' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\'':
+			b.WriteByte('\'')
+			p.pos += 2
+		case c == '\'':
+			p.pos++
+			return b.String(), nil
+		case c == '\n':
+			p.foldLineBreak(&b)
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, p.errorf(start, "unterminated single-quoted string")
+}
+
+// foldLineBreak applies quoted-scalar line folding: a single break becomes a
+// space, and each following empty line becomes a newline.
+func (p *yamlParser) foldLineBreak(b *strings.Builder) {
+	s := strings.TrimRight(b.String(), " \t")
+	b.Reset()
+	b.WriteString(s)
+	p.pos++
+	breaks := 0
+	for {
+		for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+			p.pos++
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '\n' {
+			breaks++
+			p.pos++
+			continue
+		}
+		break
+	}
+	if breaks == 0 {
+		b.WriteByte(' ')
+		return
+	}
+	b.WriteString(strings.Repeat("\n", breaks))
+}
+
+func (p *yamlParser) parseFlow() (any, error) {
+	start := p.pos
+	open := p.src[p.pos]
+	p.pos++
+	if open == '[' {
+		out := []any{}
+		for {
+			if err := p.skipFlowSpace(start); err != nil {
+				return nil, err
+			}
+			if p.src[p.pos] == ']' {
+				p.pos++
+				return out, nil
+			}
+			leave := p.enter(fmt.Sprintf("%s[%d]", p.path, len(out)), p.pos)
+			item, err := p.parseFlowItem(start)
+			leave()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+			if err := p.skipFlowSpace(start); err != nil {
+				return nil, err
+			}
+			switch p.src[p.pos] {
+			case ',':
+				p.pos++
+			case ']':
+				p.pos++
+				return out, nil
+			default:
+				return nil, p.errorf(p.pos, "expected ',' or ']' in flow sequence")
+			}
+		}
+	}
+	out := map[string]any{}
+	for {
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, err
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			return out, nil
+		}
+		keyPos := p.pos
+		k, err := p.parseFlowItem(start)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprint(k)
+		if _, dup := out[key]; dup {
+			return nil, p.errorf(keyPos, "duplicate key %q", key)
+		}
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, err
+		}
+		var v any
+		if p.src[p.pos] == ':' {
+			p.pos++
+			if err := p.skipFlowSpace(start); err != nil {
+				return nil, err
+			}
+			if c := p.src[p.pos]; c != ',' && c != '}' {
+				leave := p.enter(joinPath(p.path, key), keyPos)
+				v, err = p.parseFlowItem(start)
+				leave()
+				if err != nil {
+					return nil, err
+				}
+			}
+			if err := p.skipFlowSpace(start); err != nil {
+				return nil, err
+			}
+		}
+		out[key] = v
+		switch p.src[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return out, nil
+		default:
+			return nil, p.errorf(p.pos, "expected ',' or '}' in flow mapping")
+		}
+	}
+}
+
+// parseFlowItem parses one entry of the flow collection opened at open.
+func (p *yamlParser) parseFlowItem(open int) (any, error) {
+	anchor, forceStr, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf(open, "unterminated flow collection")
+	}
+	var v any
+	switch p.src[p.pos] {
+	case '[', '{':
+		v, err = p.parseFlow()
+	case '"':
+		v, err = p.parseDoubleQuoted()
+	case '\'':
+		v, err = p.parseSingleQuoted()
+	case '*':
+		v, err = p.parseAlias()
+	default:
+		start := p.pos
+		for p.pos < len(p.src) {
+			c := p.src[p.pos]
+			if c == ',' || c == '[' || c == ']' || c == '{' || c == '}' || c == '\n' {
+				break
+			}
+			if c == ':' && (p.pos+1 >= len(p.src) || strings.IndexByte(" \t\n,[]{}", p.src[p.pos+1]) >= 0) {
+				break
+			}
+			if c == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+				break
+			}
+			p.pos++
+		}
+		text := strings.TrimSpace(p.src[start:p.pos])
+		if text == "" {
+			return nil, p.errorf(start, "expected a flow value")
+		}
+		if forceStr {
+			v = text
+		} else {
+			v = resolveYAMLScalar(text)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = v
+	}
+	return v, nil
+}
+
+// skipFlowSpace skips whitespace, line breaks and comments inside a flow collection.
+func (p *yamlParser) skipFlowSpace(open int) error {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			p.pos++
+		case c == '#' && (p.pos == 0 || strings.IndexByte(" \t\n", p.src[p.pos-1]) >= 0):
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return nil
+		}
+	}
+	return p.errorf(open, "unterminated flow collection")
+}
+
+func (p *yamlParser) parseBlockScalar(parentIndent int) (any, error) {
+	literal := p.src[p.pos] == '|'
+	p.pos++
+	chomp := byte(0)
+	explicit := 0
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if (c == '+' || c == '-') && chomp == 0 {
+			chomp = c
+		} else if c >= '1' && c <= '9' && explicit == 0 {
+			explicit = int(c - '0')
+		} else {
+			break
+		}
+		p.pos++
+	}
+	if err := p.endLine(); err != nil {
+		return nil, err
+	}
+	indent := -1
+	if explicit > 0 {
+		indent = parentIndent + explicit
+		if parentIndent < 0 {
+			indent = explicit
+		}
+	}
+	var lines []string
+	trailing := 0
+	lastEnd := p.pos
+	for p.pos < len(p.src) {
+		lineEnd := strings.IndexByte(p.src[p.pos:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(p.src)
+		} else {
+			lineEnd += p.pos
+		}
+		line := p.src[p.pos:lineEnd]
+		spaces := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			trailing++
+		} else {
+			if indent < 0 {
+				if spaces <= parentIndent {
+					break
+				}
+				indent = spaces
+			}
+			if spaces < indent {
+				break
+			}
+			lines = append(lines, line[indent:])
+			trailing = 0
+		}
+		lastEnd = lineEnd
+		if lineEnd >= len(p.src) {
+			p.pos = lineEnd
+			break
+		}
+		p.pos = lineEnd + 1
+	}
+	// Leave pos at the end of the last consumed line so the caller's endLine consumes the break.
+	p.pos = lastEnd
+	body := lines[:len(lines)-trailing]
+	var b strings.Builder
+	for i, l := range body {
+		if i > 0 {
+			prev := body[i-1]
+			switch {
+			case literal:
+				b.WriteByte('\n')
+			case l == "":
+				b.WriteByte('\n')
+			case prev == "":
+			case strings.HasPrefix(l, " ") || strings.HasPrefix(prev, " "):
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(l)
+	}
+	text := b.String()
+	switch chomp {
+	case '-':
+	case '+':
+		if len(body) > 0 {
+			text += "\n"
+		}
+		text += strings.Repeat("\n", trailing)
+	default:
+		if len(body) > 0 {
+			text += "\n"
+		}
+	}
+	return text, nil
+}
+
+// parsePlain reads an unquoted scalar, folding continuation lines that are
+// indented deeper than the parent node.
+func (p *yamlParser) parsePlain(parentIndent int, forceStr bool) (any, error) {
+	text, err := p.readPlainLine()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.src) && p.src[p.pos] == '\n' {
+		save := p.pos
+		breaks := 0
+		i := p.pos + 1
+		contentAt := -1
+		for i < len(p.src) {
+			j := i
+			for j < len(p.src) && p.src[j] == ' ' {
+				j++
+			}
+			if j < len(p.src) && p.src[j] == '\n' {
+				breaks++
+				i = j + 1
+				continue
+			}
+			if j < len(p.src) && j-i > parentIndent && p.src[j] != '#' {
+				contentAt = j
+			}
+			break
+		}
+		if contentAt < 0 {
+			p.pos = save
+			break
+		}
+		p.pos = contentAt
+		more, err := p.readPlainLine()
+		if err != nil {
+			return nil, err
+		}
+		if breaks == 0 {
+			text += " " + more
+		} else {
+			text += strings.Repeat("\n", breaks) + more
+		}
+	}
+	if forceStr {
+		return text, nil
+	}
+	return resolveYAMLScalar(text), nil
+}
+
+// readPlainLine reads plain scalar text up to a comment or line end.
+func (p *yamlParser) readPlainLine() (string, error) {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		c := p.src[p.pos]
+		if c == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		if c == ':' && (p.pos+1 >= len(p.src) || p.src[p.pos+1] == ' ' || p.src[p.pos+1] == '\t' || p.src[p.pos+1] == '\n') {
+			return "", p.errorf(p.pos, "mapping values are not allowed here")
+		}
+		p.pos++
+	}
+	text := strings.TrimRight(p.src[start:p.pos], " \t")
+	if p.pos < len(p.src) && p.src[p.pos] == '#' {
+		for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+	return text, nil
+}
+
+func resolveYAMLScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	if yamlIntRe.MatchString(s) {
+		if i, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), 10, 64); err == nil {
+			return int(i)
+		}
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return int(i)
+		}
+	}
+	if yamlFloatRe.MatchString(s) {
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// nextContent skips blank lines and comments, leaving pos on the next content
+// byte and reporting its indentation.
+func (p *yamlParser) nextContent() (int, bool, error) {
+	for p.pos < len(p.src) {
+		i := p.pos
+		for i < len(p.src) && (p.src[i] == ' ' || p.src[i] == '\t') {
+			i++
+		}
+		if i >= len(p.src) {
+			p.pos = i
+			return 0, false, nil
+		}
+		ls := p.lineStart(i)
+		if p.src[i] == '\n' {
+			p.pos = i + 1
+			continue
+		}
+		if p.src[i] == '#' && (i == ls || p.src[i-1] == ' ' || p.src[i-1] == '\t') {
+			for i < len(p.src) && p.src[i] != '\n' {
+				i++
+			}
+			p.pos = i
+			continue
+		}
+		if strings.IndexByte(p.src[ls:i], '\t') >= 0 && strings.Trim(p.src[ls:i], " \t") == "" {
+			return 0, false, p.errorf(ls+strings.IndexByte(p.src[ls:i], '\t'), "tabs are not allowed for indentation")
+		}
+		p.pos = i
+		return i - ls, true, nil
+	}
+	return 0, false, nil
+}
+
+// endLine requires the rest of the current line to be blank or a comment.
+func (p *yamlParser) endLine() error {
+	p.skipSpaces()
+	if p.pos < len(p.src) && p.src[p.pos] == '#' {
+		for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+	if p.pos >= len(p.src) {
+		return nil
+	}
+	if p.src[p.pos] != '\n' {
+		return p.errorf(p.pos, "unexpected content after value")
+	}
+	p.pos++
+	return nil
+}
+
+func (p *yamlParser) atLineEnd() bool {
+	return p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '#'
+}
+
+func (p *yamlParser) atMarker(m string) bool {
+	if !strings.HasPrefix(p.src[p.pos:], m) {
+		return false
+	}
+	end := p.pos + len(m)
+	return end >= len(p.src) || p.src[end] == ' ' || p.src[end] == '\n'
+}
+
+func (p *yamlParser) atDocumentEnd(indent int) bool {
+	return indent == 0 && (p.atMarker("---") || p.atMarker("..."))
+}
+
+func (p *yamlParser) isSeqEntry() bool {
+	if p.pos >= len(p.src) || p.src[p.pos] != '-' {
+		return false
+	}
+	next := p.pos + 1
+	return next >= len(p.src) || p.src[next] == ' ' || p.src[next] == '\n'
+}
+
+func (p *yamlParser) isKeyLine() bool {
+	_, ok := p.keyColon()
+	return ok
+}
+
+// keyColon finds the ':' that ends a mapping key on the current line.
+func (p *yamlParser) keyColon() (int, bool) {
+	if p.pos >= len(p.src) {
+		return 0, false
+	}
+	i := p.pos
+	switch p.src[i] {
+	case '"', '\'':
+		q := p.src[i]
+		i++
+		for i < len(p.src) && p.src[i] != '\n' {
+			if q == '"' && p.src[i] == '\\' {
+				i += 2
+				continue
+			}
+			if p.src[i] == q {
+				if q == '\'' && i+1 < len(p.src) && p.src[i+1] == '\'' {
+					i += 2
+					continue
+				}
+				break
+			}
+			i++
+		}
+		if i >= len(p.src) || p.src[i] != q {
+			return 0, false
+		}
+		i++
+		for i < len(p.src) && p.src[i] == ' ' {
+			i++
+		}
+		if i < len(p.src) && p.src[i] == ':' && (i+1 >= len(p.src) || p.src[i+1] == ' ' || p.src[i+1] == '\n') {
+			return i, true
+		}
+		return 0, false
+	case '[', '{', '#', '|', '>', '*', '&', '!':
+		return 0, false
+	}
+	for ; i < len(p.src) && p.src[i] != '\n'; i++ {
+		c := p.src[i]
+		if c == '#' && i > p.pos && (p.src[i-1] == ' ' || p.src[i-1] == '\t') {
+			return 0, false
+		}
+		if c == ':' && (i+1 >= len(p.src) || p.src[i+1] == ' ' || p.src[i+1] == '\t' || p.src[i+1] == '\n') {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (p *yamlParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *yamlParser) readName() string {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n,[]{}", p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *yamlParser) readToken() string {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n", p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// enter records the source line of path and makes it current until the
+// returned func restores the parent.
+func (p *yamlParser) enter(path string, pos int) func() {
+	p.lines[path] = strings.Count(p.src[:pos], "\n") + 1
+	parent := p.path
+	p.path = path
+	return func() { p.path = parent }
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func (p *yamlParser) lineStart(pos int) int {
+	return strings.LastIndexByte(p.src[:pos], '\n') + 1
+}
+
+func (p *yamlParser) column() int {
+	return p.pos - p.lineStart(p.pos)
+}
+
+func (p *yamlParser) errorf(pos int, format string, args ...any) error {
+	if pos > len(p.src) {
+		pos = len(p.src)
+	}
+	line := strings.Count(p.src[:pos], "\n") + 1
+	col := utf8.RuneCountInString(p.src[p.lineStart(pos):pos]) + 1
+	return &YAMLError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{
+			name: "block sequence",
+			src:  "ping:\n  - 1.1.1.1\n  - \"8.8.8.8\" # cloudflare\n",
+			want: map[string]any{"ping": []any{"1.1.1.1", "8.8.8.8"}},
+		},
+		{
+			name: "sequence at key indentation",
+			src:  "ping:\n- a\n- b\nnext: 1\n",
+			want: map[string]any{"ping": []any{"a", "b"}, "next": 1},
+		},
+		{
+			name: "list of maps",
+			src:  "servers:\n  - host: a\n    port: 5201\n  -\n    host: b\n    port: 5202\n",
+			want: map[string]any{"servers": []any{
+				map[string]any{"host": "a", "port": 5201},
+				map[string]any{"host": "b", "port": 5202},
+			}},
+		},
+		{
+			name: "nested sequences",
+			src:  "m:\n  - - a\n    - b\n  - [c, d]\n",
+			want: map[string]any{"m": []any{[]any{"a", "b"}, []any{"c", "d"}}},
+		},
+		{
+			name: "quoted strings with comment and comma",
+			src:  "a: \"x # not a comment\"\nb: 'it''s, fine'\nc: [\"1,2\", '#3', plain]\n",
+			want: map[string]any{"a": "x # not a comment", "b": "it's, fine", "c": []any{"1,2", "#3", "plain"}},
+		},
+		{
+			name: "multi-line quoted",
+			src:  "a: \"first\n  second\n\n  third\"\nb: 'one\n  two'\n",
+			want: map[string]any{"a": "first second\nthird", "b": "one two"},
+		},
+		{
+			name: "escapes",
+			src:  `a: "tab\there \u00e9 \"q\""` + "\n",
+			want: map[string]any{"a": "tab\there é \"q\""},
+		},
+		{
+			name: "block scalars",
+			src:  "lit: |\n  line1\n    indented\n  line3\n\nfold: >-\n  one\n  two\n\n  three\nkeep: |+\n  x\n\nend: 1\n",
+			want: map[string]any{"lit": "line1\n  indented\nline3\n", "fold": "one two\nthree", "keep": "x\n\n", "end": 1},
+		},
+		{
+			name: "anchors aliases and merge",
+			src:  "base: &base\n  enabled: true\n  streams: 4\nhosts: &hosts [a, b]\niperf:\n  <<: *base\n  streams: 8\nping: *hosts\n",
+			want: map[string]any{
+				"base":  map[string]any{"enabled": true, "streams": 4},
+				"hosts": []any{"a", "b"},
+				"iperf": map[string]any{"enabled": true, "streams": 8},
+				"ping":  []any{"a", "b"},
+			},
+		},
+		{
+			name: "flow mapping over lines",
+			src:  "limits: {bandwidth: 1,\n  dns: 2, # comment\n  http: 3}\n",
+			want: map[string]any{"limits": map[string]any{"bandwidth": 1, "dns": 2, "http": 3}},
+		},
+		{
+			name: "scalar types",
+			src:  "i: -42\nf: 1.5\nh: 0x1f\nb: false\nn: ~\ne:\ns: !!str 123\nip: 1.1.1.1\nurl: https://example.com:8443/x\nv: \"30\"\n",
+			want: map[string]any{"i": -42, "f": 1.5, "h": 31, "b": false, "n": nil, "e": nil, "s": "123", "ip": "1.1.1.1", "url": "https://example.com:8443/x", "v": "30"},
+		},
+		{
+			name: "plain continuation and document markers",
+			src:  "---\ndesc: a long\n  value here\nurl: x\n...\n",
+			want: map[string]any{"desc": "a long value here", "url": "x"},
+		},
+		{
+			name: "empty document",
+			src:  "# only comments\n\n",
+			want: map[string]any{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := parseYAML(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %#v\nwant %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrorsCarryPosition(t *testing.T) {
+	cases := []struct {
+		name      string
+		src       string
+		line, col int
+	}{
+		{"bad indentation", "a:\n  b: 1\n    c: 2\n", 3, 6},
+		{"tab indentation", "a:\n\tb: 1\n", 2, 1},
+		{"unterminated quote", "a: 1\nb: \"open\n", 2, 4},
+		{"unknown alias", "a: *missing\n", 1, 4},
+		{"duplicate key", "a: 1\na: 2\n", 2, 1},
+		{"trailing content", "a: \"x\" y\n", 1, 8},
+		{"unterminated flow", "a: [1, 2\n", 1, 4},
+		{"flow ends after anchor", "ping: [&a", 1, 7},
+		{"flow ends after tag", "ping: [1, !!str", 1, 7},
+		{"flow mapping ends after tag", "a: {b: !!str", 1, 4},
+		{"flow missing comma", "a: [1, 2\nb: 3\n", 2, 1},
+		{"nested mapping value", "a: b: c\n", 1, 5},
+		{"sequence in mapping", "a: 1\n- b\n", 2, 1},
+		{"root scalar", "just text\n", 1, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := parseYAML(tc.src)
+			var ye *YAMLError
+			if !errors.As(err, &ye) {
+				t.Fatalf("expected YAMLError, got %v", err)
+			}
+			if ye.Line != tc.line || ye.Column != tc.col {
+				t.Fatalf("expected line %d column %d, got %v", tc.line, tc.col, err)
+			}
+		})
+	}
+}
+
+func TestParseYAMLRecordsKeyLines(t *testing.T) {
+	src := "targets:\n  ping:\n    - a\n    - b\n\nlimits: {dns: 1,\n  http: 2}\n"
+	_, lines, err := parseYAML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"targets": 1, "targets.ping": 2, "targets.ping[0]": 3, "targets.ping[1]": 4, "limits": 6, "limits.dns": 6, "limits.http": 7}
+	for path, line := range want {
+		if lines[path] != line {
+			t.Fatalf("%s: expected line %d, got %d (%v)", path, line, lines[path], lines)
+		}
+	}
+}
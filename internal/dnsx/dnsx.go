@@ -0,0 +1,263 @@
+package dnsx
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	TypeA    uint16 = 1
+	TypeAAAA uint16 = 28
+	classIN  uint16 = 1
+
+	udpBufSize = 4096
+)
+
+var rcodeNames = map[int]string{
+	0: "NOERROR",
+	1: "FORMERR",
+	2: "SERVFAIL",
+	3: "NXDOMAIN",
+	4: "NOTIMP",
+	5: "REFUSED",
+}
+
+type Result struct {
+	RTT       time.Duration
+	Transport string
+	Rcode     int
+	Answers   int
+	TTLs      []uint32
+	Addrs     []string
+	Truncated bool
+}
+
+func (r Result) RcodeName() string {
+	if n, ok := rcodeNames[r.Rcode]; ok {
+		return n
+	}
+	return fmt.Sprintf("RCODE%d", r.Rcode)
+}
+
+// Query sends a single recursive question to server over UDP and retries over TCP
+// when the UDP answer is truncated. RTT covers the whole exchange, including fallback.
+func Query(ctx context.Context, server, name string, qtype uint16) (Result, error) {
+	addr := serverAddr(server)
+	id := uint16(rand.N(1 << 16))
+	msg, err := buildQuery(id, name, qtype)
+	if err != nil {
+		return Result{}, err
+	}
+	start := time.Now()
+	raw, err := exchangeUDP(ctx, addr, msg)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := parseResponse(raw, id)
+	if err != nil {
+		return Result{}, err
+	}
+	res.Transport = "udp"
+	if res.Truncated {
+		raw, err = exchangeTCP(ctx, addr, msg)
+		if err != nil {
+			return Result{}, fmt.Errorf("tcp fallback: %w", err)
+		}
+		tcpRes, err := parseResponse(raw, id)
+		if err != nil {
+			return Result{}, err
+		}
+		tcpRes.Transport = "tcp"
+		// Keep the flag so callers can see the UDP answer did not fit.
+		tcpRes.Truncated = true
+		res = tcpRes
+	}
+	res.RTT = time.Since(start)
+	return res, nil
+}
+
+// SystemServers returns the nameservers listed in a resolv.conf-style file.
+func SystemServers(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			out = append(out, fields[1])
+		}
+	}
+	return out
+}
+
+func serverAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
+
+func exchangeUDP(ctx context.Context, addr string, msg []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	setDeadline(ctx, conn)
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, udpBufSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	return buf[:n], nil
+}
+
+func exchangeTCP(ctx context.Context, addr string, msg []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	setDeadline(ctx, conn)
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+	framed := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(framed, uint16(len(msg)))
+	copy(framed[2:], msg)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	out := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(conn, out); err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	return out, nil
+}
+
+func setDeadline(ctx context.Context, conn net.Conn) {
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+}
+
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func buildQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil, errors.New("empty query name")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid label in %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+	return msg, nil
+}
+
+func parseResponse(msg []byte, id uint16) (Result, error) {
+	var res Result
+	if len(msg) < 12 {
+		return res, errors.New("short dns response")
+	}
+	if binary.BigEndian.Uint16(msg[0:]) != id {
+		return res, errors.New("dns response id mismatch")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return res, errors.New("dns message is not a response")
+	}
+	res.Truncated = flags&0x0200 != 0
+	res.Rcode = int(flags & 0x000f)
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	an := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	var err error
+	for i := 0; i < qd; i++ {
+		if off, err = skipName(msg, off); err != nil {
+			return res, err
+		}
+		off += 4
+	}
+	for i := 0; i < an; i++ {
+		if off, err = skipName(msg, off); err != nil {
+			return res, err
+		}
+		if off+10 > len(msg) {
+			return res, errors.New("truncated answer record")
+		}
+		typ := binary.BigEndian.Uint16(msg[off:])
+		ttl := binary.BigEndian.Uint32(msg[off+4:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return res, errors.New("truncated answer rdata")
+		}
+		rdata := msg[off : off+rdlen]
+		off += rdlen
+		res.Answers++
+		res.TTLs = append(res.TTLs, ttl)
+		switch {
+		case typ == TypeA && rdlen == net.IPv4len:
+			res.Addrs = append(res.Addrs, net.IP(rdata).String())
+		case typ == TypeAAAA && rdlen == net.IPv6len:
+			res.Addrs = append(res.Addrs, net.IP(rdata).String())
+		}
+	}
+	return res, nil
+}
+
+func skipName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errors.New("name overflows message")
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xc0 == 0xc0:
+			return off + 2, nil
+		default:
+			off += 1 + l
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package dnsx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"netcheck/internal/dnsx/dnsxtest"
+)
+
+func TestQueryUDP(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{TTL: 300, Addrs: []string{"93.184.216.34", "93.184.216.35"}}
+	})
+	defer srv.Close()
+	res, err := Query(context.Background(), srv.Addr, "example.com", TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transport != "udp" || res.Truncated || res.RcodeName() != "NOERROR" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.Answers != 2 || res.TTLs[0] != 300 || res.Addrs[0] != "93.184.216.34" {
+		t.Fatalf("unexpected answers: %+v", res)
+	}
+	if res.RTT <= 0 {
+		t.Fatalf("expected positive rtt, got %s", res.RTT)
+	}
+}
+
+func TestQueryFallsBackToTCPWhenTruncated(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{TTL: 60, Addrs: []string{"10.0.0.1"}, TruncateUDP: true}
+	})
+	defer srv.Close()
+	res, err := Query(context.Background(), srv.Addr, "big.example", TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transport != "tcp" || !res.Truncated || res.Answers != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if q := srv.Queries(); len(q) != 2 || q[0] != "udp big.example" || q[1] != "tcp big.example" {
+		t.Fatalf("unexpected queries: %v", q)
+	}
+}
+
+func TestQueryNXDOMAIN(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{Rcode: 3}
+	})
+	defer srv.Close()
+	res, err := Query(context.Background(), srv.Addr, "missing.example", TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RcodeName() != "NXDOMAIN" || res.Answers != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestQueryRespectsContextDeadline(t *testing.T) {
+	srv := dnsxtest.NewServer(func(name string, qtype uint16) dnsxtest.Answer {
+		return dnsxtest.Answer{Delay: 300 * time.Millisecond}
+	})
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Query(ctx, srv.Addr, "slow.example", TypeA); err == nil {
+		t.Fatal("expected timeout error")
+	}
+}
+
+func TestSystemServers(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(p, []byte("# comment\nsearch lan\nnameserver 192.168.1.1\nnameserver ::1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := SystemServers(p)
+	if len(got) != 2 || got[0] != "192.168.1.1" || got[1] != "::1" {
+		t.Fatalf("unexpected servers: %v", got)
+	}
+	if serverAddr("::1") != "[::1]:53" || serverAddr("10.0.0.1:5353") != "10.0.0.1:5353" {
+		t.Fatalf("unexpected server address normalization")
+	}
+}
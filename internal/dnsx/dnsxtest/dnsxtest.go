@@ -0,0 +1,161 @@
+// Package dnsxtest provides an in-process DNS server for tests.
+package dnsxtest
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Answer describes how the server replies to one question.
+type Answer struct {
+	Rcode int
+	TTL   uint32
+	Addrs []string
+	// TruncateUDP sets the TC bit and drops records on UDP so clients retry over TCP.
+	TruncateUDP bool
+	Delay       time.Duration
+}
+
+type Handler func(name string, qtype uint16) Answer
+
+type Server struct {
+	Addr string
+
+	udp     net.PacketConn
+	tcp     net.Listener
+	handler Handler
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	queries []string
+}
+
+// NewServer listens on UDP and TCP on the same loopback port.
+func NewServer(h Handler) *Server {
+	for attempt := 0; attempt < 20; attempt++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			panic(err)
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			_ = udp.Close()
+			continue
+		}
+		s := &Server{Addr: udp.LocalAddr().String(), udp: udp, tcp: tcp, handler: h}
+		s.wg.Add(2)
+		go s.serveUDP()
+		go s.serveTCP()
+		return s
+	}
+	panic("dnsxtest: unable to bind udp and tcp on the same port")
+}
+
+func (s *Server) Close() {
+	_ = s.udp.Close()
+	_ = s.tcp.Close()
+	s.wg.Wait()
+}
+
+// Queries returns "transport name" entries for every question received.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func (s *Server) serveUDP() {
+	defer s.wg.Done()
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp := s.respond(buf[:n], "udp")
+		if resp != nil {
+			_, _ = s.udp.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (s *Server) serveTCP() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(conn, lenBuf[:]); err == nil {
+			msg := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+			if _, err := io.ReadFull(conn, msg); err == nil {
+				if resp := s.respond(msg, "tcp"); resp != nil {
+					out := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+					_, _ = conn.Write(append(out, resp...))
+				}
+			}
+		}
+		_ = conn.Close()
+	}
+}
+
+func (s *Server) respond(q []byte, transport string) []byte {
+	if len(q) < 12 {
+		return nil
+	}
+	off := 12
+	var labels []string
+	for off < len(q) && q[off] != 0 {
+		l := int(q[off])
+		if off+1+l > len(q) {
+			return nil
+		}
+		labels = append(labels, string(q[off+1:off+1+l]))
+		off += 1 + l
+	}
+	off++
+	if off+4 > len(q) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(q[off:])
+	question := q[12 : off+4]
+	name := strings.Join(labels, ".")
+	s.mu.Lock()
+	s.queries = append(s.queries, transport+" "+name)
+	s.mu.Unlock()
+
+	ans := s.handler(name, qtype)
+	if ans.Delay > 0 {
+		time.Sleep(ans.Delay)
+	}
+	flags := uint16(0x8180) | uint16(ans.Rcode&0x0f)
+	addrs := ans.Addrs
+	if ans.TruncateUDP && transport == "udp" {
+		flags |= 0x0200
+		addrs = nil
+	}
+	resp := make([]byte, 12)
+	copy(resp[0:2], q[0:2])
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(addrs)))
+	resp = append(resp, question...)
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		rtype, rdata := uint16(28), ip.To16()
+		if v4 := ip.To4(); v4 != nil {
+			rtype, rdata = 1, v4
+		}
+		resp = append(resp, 0xc0, 0x0c)
+		resp = binary.BigEndian.AppendUint16(resp, rtype)
+		resp = binary.BigEndian.AppendUint16(resp, 1)
+		resp = binary.BigEndian.AppendUint32(resp, ans.TTL)
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+		resp = append(resp, rdata...)
+	}
+	return resp
+}
@@ -13,13 +13,16 @@ import (
 var manFS embed.FS
 
 var topics = map[string]string{
-	"netcheck":    "man/netcheck.md",
-	"run":         "man/run.md",
-	"soak":        "man/soak.md",
-	"compare":     "man/compare.md",
-	"config":      "man/config.md",
-	"exit-codes":  "man/exit-codes.md",
-	"json-schema": "man/json-schema.md",
+	"netcheck":             "man/netcheck.md",
+	"run":                  "man/run.md",
+	"soak":                 "man/soak.md",
+	"exporter":             "man/exporter.md",
+	"serve-responsiveness": "man/serve-responsiveness.md",
+	"compare":              "man/compare.md",
+	"explain-score":        "man/explain-score.md",
+	"config":               "man/config.md",
+	"exit-codes":           "man/exit-codes.md",
+	"json-schema":          "man/json-schema.md",
 }
 
 func Topics() []string {
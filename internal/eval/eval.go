@@ -1,6 +1,13 @@
 package eval
 
-import "netcheck/internal/model"
+import (
+	"fmt"
+	"math"
+	"netcheck/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type Thresholds struct {
 	Pass float64
@@ -27,78 +34,193 @@ func LowerIsBetter(value, passMax, warnMax float64) model.Status {
 	return model.StatusFail
 }
 
-func Score(checks []model.CheckResult) int {
-	if len(checks) == 0 {
-		return 0
+// BufferbloatGrade maps a loaded-latency increase to a letter grade.
+func BufferbloatGrade(deltaMs float64) string {
+	switch {
+	case deltaMs < 5:
+		return "A+"
+	case deltaMs < 30:
+		return "A"
+	case deltaMs < 60:
+		return "B"
+	case deltaMs < 200:
+		return "C"
+	case deltaMs < 400:
+		return "D"
+	default:
+		return "F"
 	}
-	weights := map[string]float64{
-		"reliability": 35,
-		"latency":     25,
-		"dns":         10,
-		"http":        10,
-		"throughput":  20,
+}
+
+// DefaultScoring is the built-in model: reliability 35, latency 25, dns 10,
+// http 10 and throughput 20, with skips worth half a pass.
+func DefaultScoring() model.ScoringModel {
+	return model.ScoringModel{
+		Weights: map[string]float64{
+			"reliability": 35,
+			"latency":     25,
+			"dns":         10,
+			"http":        10,
+			"throughput":  20,
+		},
+		Groups: map[string]string{
+			"local":          "reliability",
+			"reachability":   "reliability",
+			"path":           "reliability",
+			"bufferbloat":    "latency",
+			"dns":            "dns",
+			"http":           "http",
+			"tls":            "http",
+			"bandwidth":      "throughput",
+			"responsiveness": "latency",
+		},
+		StatusValues: map[model.Status]float64{
+			model.StatusPass: 1,
+			model.StatusWarn: 0.6,
+			model.StatusFail: 0,
+			model.StatusSkip: 0.5,
+		},
 	}
-	catScores := map[string][]float64{}
-	for _, c := range checks {
-		cat := categoryForGroup(c.Group)
-		if cat == "" {
-			continue
-		}
-		catScores[cat] = append(catScores[cat], statusToScore(c.Status))
+}
+
+// Score uses DefaultScoring.
+func Score(checks []model.CheckResult) int {
+	return ScoreWith(checks, DefaultScoring())
+}
+
+// ScoreWith scores checks under m.
+func ScoreWith(checks []model.CheckResult, m model.ScoringModel) int {
+	return Breakdown(checks, m, nil).Score
+}
+
+// Limit is the pass threshold a check metric is judged against.
+type Limit struct {
+	Metric        string
+	Pass          float64
+	LowerIsBetter bool
+}
+
+// Breakdown scores checks under m and records where points were lost.
+// Categories without any counted check are left out and the remaining
+// weights renormalized. limits, when set, returns the thresholds of a group
+// and is used to explain non-pass results.
+func Breakdown(checks []model.CheckResult, m model.ScoringModel, limits func(group string) []Limit) model.ScoreBreakdown {
+	type acc struct {
+		sum, weight float64
+		checks      []model.CheckResult
 	}
-	var weightedTotal float64
-	var totalWeight float64
-	for cat, scores := range catScores {
-		w := weights[cat]
-		if w == 0 || len(scores) == 0 {
+	cats := map[string]*acc{}
+	var order []string
+	for _, c := range checks {
+		cat := m.Category(c.Group)
+		if cat == "" || m.Weights[cat] <= 0 || !counted(c, m) {
 			continue
 		}
-		var sum float64
-		for _, sc := range scores {
-			sum += sc
+		a, ok := cats[cat]
+		if !ok {
+			a = &acc{}
+			cats[cat] = a
+			order = append(order, cat)
 		}
-		weightedTotal += (sum / float64(len(scores))) * w
-		totalWeight += w
+		w := m.CheckWeight(c.ID)
+		a.sum += m.StatusValues[c.Status] * w
+		a.weight += w
+		a.checks = append(a.checks, c)
 	}
-	if totalWeight == 0 {
-		return 0
+	sort.Strings(order)
+	var weightedTotal, totalWeight float64
+	for _, cat := range order {
+		weightedTotal += cats[cat].sum / cats[cat].weight * m.Weights[cat]
+		totalWeight += m.Weights[cat]
 	}
-	s := int((weightedTotal / totalWeight) * 100)
-	if s < 0 {
-		return 0
+	out := model.ScoreBreakdown{Categories: []model.CategoryScore{}}
+	if totalWeight == 0 {
+		return out
 	}
-	if s > 100 {
-		return 100
+	out.Score = min(max(int((weightedTotal/totalWeight)*100), 0), 100)
+	for _, cat := range order {
+		a := cats[cat]
+		share := m.Weights[cat] / totalWeight * 100
+		cs := model.CategoryScore{Category: cat, Weight: m.Weights[cat], MaxPoints: round2(share)}
+		var earned float64
+		for _, c := range a.checks {
+			w := m.CheckWeight(c.ID)
+			maxPts := share * w / a.weight
+			pts := maxPts * m.StatusValues[c.Status]
+			cp := model.CheckPoints{ID: c.ID, Group: c.Group, Status: c.Status, Weight: w, MaxPoints: round2(maxPts), Points: round2(pts), Lost: round2(maxPts - pts)}
+			if c.Status != model.StatusPass {
+				var lim []Limit
+				if limits != nil {
+					lim = limits(c.Group)
+				}
+				cp.Reason = Reason(c, lim)
+			}
+			earned += pts
+			cs.Checks = append(cs.Checks, cp)
+		}
+		cs.Points = round2(earned)
+		cs.Lost = round2(share - earned)
+		out.Categories = append(out.Categories, cs)
 	}
-	return s
+	return out
 }
 
-func statusToScore(s model.Status) float64 {
-	switch s {
-	case model.StatusPass:
-		return 1
-	case model.StatusWarn:
-		return 0.6
-	case model.StatusSkip:
-		return 0.5
-	default:
+// GroupScore is the 0-100 value of one group's checks under m, using the
+// same status values, check weights and skip handling as the report score.
+func GroupScore(checks []model.CheckResult, m model.ScoringModel) int {
+	var sum, weight float64
+	for _, c := range checks {
+		if !counted(c, m) {
+			continue
+		}
+		w := m.CheckWeight(c.ID)
+		sum += m.StatusValues[c.Status] * w
+		weight += w
+	}
+	if weight == 0 {
 		return 0
 	}
+	return int(math.Round(sum / weight * 100))
 }
 
-func categoryForGroup(group string) string {
-	switch group {
-	case "local", "reachability", "path":
-		return "reliability"
-	case "bufferbloat":
-		return "latency"
-	case "dns":
-		return "dns"
-	case "http":
-		return "http"
-	case "bandwidth":
-		return "throughput"
-	default:
-		return ""
+func counted(c model.CheckResult, m model.ScoringModel) bool {
+	if m.ExcludeSkips && c.Status == model.StatusSkip {
+		return false
+	}
+	return m.CheckWeight(c.ID) > 0
+}
+
+// Reason explains a non-pass result: the metrics that missed their pass
+// threshold, else the blocking check or the check's own error.
+func Reason(c model.CheckResult, limits []Limit) string {
+	var parts []string
+	for _, l := range limits {
+		v, ok := c.Metrics[l.Metric].(float64)
+		if !ok {
+			continue
+		}
+		switch {
+		case l.LowerIsBetter && v > l.Pass:
+			parts = append(parts, fmt.Sprintf("%s %s > %s", l.Metric, formatNum(v), formatNum(l.Pass)))
+		case l.LowerIsBetter && v == l.Pass:
+			parts = append(parts, fmt.Sprintf("%s %s >= %s", l.Metric, formatNum(v), formatNum(l.Pass)))
+		case !l.LowerIsBetter && v < l.Pass:
+			parts = append(parts, fmt.Sprintf("%s %s < %s", l.Metric, formatNum(v), formatNum(l.Pass)))
+		}
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, ", ")
 	}
+	if c.BlockedBy != "" {
+		return "blocked by " + c.BlockedBy
+	}
+	return c.Error
+}
+
+func formatNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
 }
@@ -42,3 +42,97 @@ func TestUpperIsBetter(t *testing.T) {
 		t.Fatalf("expected fail, got %s", got)
 	}
 }
+
+func TestBufferbloatGrade(t *testing.T) {
+	for delta, want := range map[float64]string{-2: "A+", 4.9: "A+", 5: "A", 45: "B", 150: "C", 399: "D", 400: "F"} {
+		if got := BufferbloatGrade(delta); got != want {
+			t.Fatalf("BufferbloatGrade(%v) = %s, want %s", delta, got, want)
+		}
+	}
+}
+
+func TestScoreWithModel(t *testing.T) {
+	checks := []model.CheckResult{
+		{ID: "reachability.a", Group: "reachability", Status: model.StatusPass},
+		{ID: "reachability.b", Group: "reachability", Status: model.StatusFail},
+		{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusSkip},
+		{ID: "voip.x", Group: "voip", Status: model.StatusWarn},
+	}
+	if got, want := ScoreWith(checks, DefaultScoring()), Score(checks); got != want {
+		t.Fatalf("default model should match Score: %d vs %d", got, want)
+	}
+	m := DefaultScoring()
+	m.ExcludeSkips = true
+	// Only reliability remains: (1+0)/2.
+	if got := ScoreWith(checks, m); got != 50 {
+		t.Fatalf("expected skips to drop out of the score, got %d", got)
+	}
+	m.CheckWeights = map[string]float64{"reachability.a": 3}
+	if got := ScoreWith(checks, m); got != 75 {
+		t.Fatalf("expected per-check weight to apply, got %d", got)
+	}
+	m.CheckWeights = map[string]float64{"reachability.b": 0}
+	if got := ScoreWith(checks, m); got != 100 {
+		t.Fatalf("a zero check weight should remove the check, got %d", got)
+	}
+	m.CheckWeights = nil
+	m.DefaultCategory = "latency"
+	// Unknown groups fall into the default category: reliability 0.5*35, latency 0.6*25.
+	if got := ScoreWith(checks, m); got != 54 {
+		t.Fatalf("expected new groups to be scored via default_category, got %d", got)
+	}
+	m.StatusValues = map[model.Status]float64{model.StatusPass: 1, model.StatusWarn: 1}
+	if got := ScoreWith(checks, m); got != 70 {
+		t.Fatalf("expected custom status values, got %d", got)
+	}
+}
+
+func TestBreakdownSplitsCategoryPoints(t *testing.T) {
+	checks := []model.CheckResult{
+		{ID: "reachability.1.1.1.1", Group: "reachability", Status: model.StatusPass},
+		{ID: "reachability.8.8.8.8", Group: "reachability", Status: model.StatusWarn, Metrics: map[string]any{"rtt_p95_ms": 62.0, "loss_pct": 0.0}},
+		{ID: "dns.google.com", Group: "dns", Status: model.StatusFail, Error: "timeout"},
+	}
+	limits := func(group string) []Limit {
+		if group != "reachability" {
+			return nil
+		}
+		return []Limit{{Metric: "loss_pct", Pass: 0.5, LowerIsBetter: true}, {Metric: "rtt_p95_ms", Pass: 40, LowerIsBetter: true}}
+	}
+	b := Breakdown(checks, DefaultScoring(), limits)
+	if b.Score != ScoreWith(checks, DefaultScoring()) || b.Score != 62 {
+		t.Fatalf("breakdown score should match ScoreWith, got %d", b.Score)
+	}
+	if len(b.Categories) != 2 || b.Categories[0].Category != "dns" || b.Categories[1].Category != "reliability" {
+		t.Fatalf("unexpected categories: %+v", b.Categories)
+	}
+	dns, rel := b.Categories[0], b.Categories[1]
+	if dns.MaxPoints != 22.22 || dns.Points != 0 || dns.Lost != 22.22 || dns.Checks[0].Reason != "timeout" {
+		t.Fatalf("unexpected dns category: %+v", dns)
+	}
+	warn := rel.Checks[1]
+	if warn.MaxPoints != 38.89 || warn.Points != 23.33 || warn.Lost != 15.56 || warn.Reason != "rtt_p95_ms 62 > 40" {
+		t.Fatalf("unexpected check points: %+v", warn)
+	}
+	if rel.Checks[0].Reason != "" || rel.Checks[0].Lost != 0 {
+		t.Fatalf("passing checks lose nothing: %+v", rel.Checks[0])
+	}
+}
+
+func TestReason(t *testing.T) {
+	limits := []Limit{{Metric: "download_pct_of_expected", Pass: 80}, {Metric: "delta_ms", Pass: 30, LowerIsBetter: true}}
+	cases := []struct {
+		c    model.CheckResult
+		want string
+	}{
+		{model.CheckResult{Metrics: map[string]any{"download_pct_of_expected": 61.25, "delta_ms": 45.0}}, "download_pct_of_expected 61.3 < 80, delta_ms 45 > 30"},
+		{model.CheckResult{Metrics: map[string]any{"delta_ms": 30.0}}, "delta_ms 30 >= 30"},
+		{model.CheckResult{BlockedBy: "local.gateway", Error: "blocked"}, "blocked by local.gateway"},
+		{model.CheckResult{Error: "speedtest-cli not found"}, "speedtest-cli not found"},
+	}
+	for _, tc := range cases {
+		if got := Reason(tc.c, limits); got != tc.want {
+			t.Fatalf("Reason(%+v) = %q, want %q", tc.c, got, tc.want)
+		}
+	}
+}
@@ -2,16 +2,35 @@ package events
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"netcheck/internal/model"
 	"time"
 )
 
+// SchemaVersion is bumped whenever an event envelope or payload field is
+// removed or changes meaning; adding fields does not bump it.
+const SchemaVersion = "events/v2"
+
 type Event struct {
-	EventType string         `json:"event_type"`
-	Timestamp time.Time      `json:"timestamp"`
-	RunID     string         `json:"run_id"`
-	Sequence  int            `json:"sequence"`
-	Payload   map[string]any `json:"payload,omitempty"`
+	SchemaVersion string         `json:"schema_version"`
+	EventType     string         `json:"event_type"`
+	Timestamp     time.Time      `json:"timestamp"`
+	RunID         string         `json:"run_id"`
+	Sequence      int            `json:"sequence"`
+	Payload       map[string]any `json:"payload,omitempty"`
+}
+
+// PayloadFields lists the payload keys every event of a type carries. Keys
+// that are only present sometimes (error, raw) are documented but not listed.
+var PayloadFields = map[string][]string{
+	"run_started":      {"command"},
+	"check_result":     {"interval", "id", "group", "status", "metrics", "duration_ms"},
+	"interval_summary": {"interval", "groups", "summary", "score"},
+	"outage_started":   {"cause", "affected_groups", "start", "first_interval"},
+	"outage_ended":     {"cause", "affected_groups", "start", "end", "duration_sec", "first_interval", "last_interval"},
+	"run_summary":      {"done", "intervals", "checks", "outages"},
+	"run_finished":     {},
 }
 
 type Writer struct {
@@ -26,7 +45,7 @@ func NewWriter(w io.Writer) *Writer {
 
 func (wr *Writer) Emit(eventType, runID string, payload map[string]any) error {
 	wr.seq++
-	e := Event{EventType: eventType, Timestamp: wr.now(), RunID: runID, Sequence: wr.seq, Payload: payload}
+	e := Event{SchemaVersion: SchemaVersion, EventType: eventType, Timestamp: wr.now(), RunID: runID, Sequence: wr.seq, Payload: payload}
 	b, err := json.Marshal(e)
 	if err != nil {
 		return err
@@ -40,3 +59,82 @@ func (wr *Writer) SetNow(now func() time.Time) {
 		wr.now = now
 	}
 }
+
+// CheckResult builds the check_result payload for one check of an interval.
+// Raw tool output is large, so it is only attached when raw is set.
+func CheckResult(interval int, c model.CheckResult, raw bool) map[string]any {
+	metrics := c.Metrics
+	if metrics == nil {
+		metrics = map[string]any{}
+	}
+	p := map[string]any{
+		"interval":    interval,
+		"id":          c.ID,
+		"group":       c.Group,
+		"target":      c.Target,
+		"status":      c.Status,
+		"metrics":     metrics,
+		"duration_ms": c.DurationMS,
+	}
+	if c.Error != "" {
+		p["error"] = c.Error
+	}
+	if c.BlockedBy != "" {
+		p["blocked_by"] = c.BlockedBy
+	}
+	if raw && c.Raw != "" {
+		p["raw"] = c.Raw
+	}
+	return p
+}
+
+// Payload flattens a struct into an event payload using its JSON field names.
+func Payload(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var p map[string]any
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks one JSONL line against the envelope and the PayloadFields
+// of its event type.
+func Validate(line []byte) error {
+	var e struct {
+		SchemaVersion *string        `json:"schema_version"`
+		EventType     *string        `json:"event_type"`
+		Timestamp     *time.Time     `json:"timestamp"`
+		RunID         *string        `json:"run_id"`
+		Sequence      *int           `json:"sequence"`
+		Payload       map[string]any `json:"payload"`
+	}
+	if err := json.Unmarshal(line, &e); err != nil {
+		return err
+	}
+	switch {
+	case e.SchemaVersion == nil || *e.SchemaVersion != SchemaVersion:
+		return fmt.Errorf("schema_version must be %q", SchemaVersion)
+	case e.EventType == nil:
+		return fmt.Errorf("missing event_type")
+	case e.Timestamp == nil:
+		return fmt.Errorf("%s: missing timestamp", *e.EventType)
+	case e.RunID == nil:
+		return fmt.Errorf("%s: missing run_id", *e.EventType)
+	case e.Sequence == nil || *e.Sequence < 1:
+		return fmt.Errorf("%s: sequence must be at least 1", *e.EventType)
+	}
+	fields, ok := PayloadFields[*e.EventType]
+	if !ok {
+		return fmt.Errorf("unknown event_type %q", *e.EventType)
+	}
+	for _, f := range fields {
+		if _, ok := e.Payload[f]; !ok {
+			return fmt.Errorf("%s: payload missing %s", *e.EventType, f)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ package events
 
 import (
 	"bytes"
+	"encoding/json"
+	"netcheck/internal/docs"
+	"netcheck/internal/model"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,8 +28,8 @@ func TestEmitGoldenJSONL(t *testing.T) {
 	var b bytes.Buffer
 	w := NewWriter(&b)
 	w.SetNow(func() time.Time { return time.Unix(1700000000, 0).UTC() })
-	_ = w.Emit("run_started", "r1", map[string]any{"cmd": "soak"})
-	_ = w.Emit("check_result", "r1", map[string]any{"id": "dns.google.com", "status": "pass"})
+	_ = w.Emit("run_started", "r1", map[string]any{"command": "soak"})
+	_ = w.Emit("check_result", "r1", CheckResult(1, model.CheckResult{ID: "dns.google.com", Group: "dns", Status: model.StatusPass, Metrics: map[string]any{"query_ms": 12.5}, DurationMS: 13, Raw: "dig output"}, false))
 
 	gold := filepath.Join("..", "..", "testdata", "golden", "events.jsonl")
 	if os.Getenv("UPDATE_GOLDEN") == "1" {
@@ -40,3 +43,64 @@ func TestEmitGoldenJSONL(t *testing.T) {
 		t.Fatalf("events jsonl mismatch")
 	}
 }
+
+func TestEmittedEventsMatchSchema(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	failed := model.CheckResult{ID: "http.example", Group: "http", Target: "https://example.com", Status: model.StatusFail, Error: "timeout", DurationMS: 5000, Raw: "curl: (28)"}
+	_ = w.Emit("run_started", "r1", map[string]any{"command": "soak"})
+	_ = w.Emit("check_result", "r1", CheckResult(1, failed, true))
+	_ = w.Emit("interval_summary", "r1", map[string]any{"interval": 1, "groups": []string{"http"}, "summary": model.Summary{Fail: 1}, "score": 0})
+	_ = w.Emit("run_summary", "r1", map[string]any{"done": true, "intervals": 1, "checks": []any{}, "outages": map[string]any{"count": 0}})
+	_ = w.Emit("run_finished", "r1", map[string]any{"duration_sec": 5})
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	for _, line := range lines {
+		if err := Validate([]byte(line)); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Payload["error"] != "timeout" || e.Payload["raw"] != "curl: (28)" || e.Payload["duration_ms"] != float64(5000) {
+		t.Fatalf("unexpected check_result payload: %+v", e.Payload)
+	}
+	if _, ok := CheckResult(1, failed, false)["raw"]; ok {
+		t.Fatal("raw output must only be attached on request")
+	}
+}
+
+func TestValidateRejectsBadEvents(t *testing.T) {
+	cases := map[string]string{
+		"version": `{"schema_version":"events/v0","event_type":"run_finished","timestamp":"2023-11-14T22:13:20Z","run_id":"r","sequence":1}`,
+		"type":    `{"schema_version":"events/v2","event_type":"bogus","timestamp":"2023-11-14T22:13:20Z","run_id":"r","sequence":1}`,
+		"seq":     `{"schema_version":"events/v2","event_type":"run_finished","timestamp":"2023-11-14T22:13:20Z","run_id":"r"}`,
+		"payload": `{"schema_version":"events/v2","event_type":"check_result","timestamp":"2023-11-14T22:13:20Z","run_id":"r","sequence":1,"payload":{"id":"x","status":"pass"}}`,
+	}
+	for name, line := range cases {
+		if err := Validate([]byte(line)); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestSchemaIsDocumented(t *testing.T) {
+	page, err := docs.Get("json-schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page, SchemaVersion) {
+		t.Fatalf("json-schema manual does not mention %s", SchemaVersion)
+	}
+	for typ, fields := range PayloadFields {
+		if !strings.Contains(page, "`"+typ+"`") {
+			t.Fatalf("event type %s is not documented", typ)
+		}
+		for _, f := range fields {
+			if !strings.Contains(page, "`"+f+"`") {
+				t.Fatalf("%s payload field %s is not documented", typ, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package execx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CassetteVersion is written to every cassette and checked on load.
+const CassetteVersion = 1
+
+// Interaction is one recorded command execution.
+type Interaction struct {
+	Command    string   `json:"command"`
+	Args       []string `json:"args"`
+	Stdout     string   `json:"stdout"`
+	Stderr     string   `json:"stderr"`
+	ExitCode   int      `json:"exit_code"`
+	Error      string   `json:"error,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// Cassette is the on-disk format shared by Recorder and Replayer. Paths keeps
+// LookPath answers; an empty value means the tool was not found.
+type Cassette struct {
+	Version      int               `json:"version"`
+	GOOS         string            `json:"goos"`
+	RecordedAt   time.Time         `json:"recorded_at"`
+	Paths        map[string]string `json:"paths"`
+	Interactions []Interaction     `json:"interactions"`
+}
+
+// Recorder wraps another executor and keeps every call for Save.
+type Recorder struct {
+	inner Executor
+	mu    sync.Mutex
+	c     Cassette
+}
+
+func NewRecorder(inner Executor) *Recorder {
+	return &Recorder{inner: inner, c: Cassette{Version: CassetteVersion, GOOS: runtime.GOOS, RecordedAt: time.Now().UTC(), Paths: map[string]string{}}}
+}
+
+func (r *Recorder) Run(ctx context.Context, name string, args ...string) Result {
+	res := r.inner.Run(ctx, name, args...)
+	it := Interaction{Command: name, Args: append([]string{}, args...), Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode, DurationMS: res.Duration.Milliseconds()}
+	if res.Err != nil {
+		it.Error = res.Err.Error()
+	}
+	r.mu.Lock()
+	r.c.Interactions = append(r.c.Interactions, it)
+	r.mu.Unlock()
+	return res
+}
+
+func (r *Recorder) LookPath(file string) (string, error) {
+	p, err := r.inner.LookPath(file)
+	r.mu.Lock()
+	r.c.Paths[file] = p
+	if err != nil {
+		r.c.Paths[file] = ""
+	}
+	r.mu.Unlock()
+	return p, err
+}
+
+// Save writes everything recorded so far to path.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.c, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Replayer serves a recorded cassette. Calls with the same command and args
+// are answered in recording order; once those run out the last one repeats,
+// so a soak can replay more intervals than were recorded.
+type Replayer struct {
+	mu    sync.Mutex
+	c     Cassette
+	byKey map[string][]Interaction
+	next  map[string]int
+}
+
+func LoadCassette(path string) (*Replayer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if c.Version != CassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", c.Version, path)
+	}
+	return NewReplayer(c), nil
+}
+
+func NewReplayer(c Cassette) *Replayer {
+	p := &Replayer{c: c, byKey: map[string][]Interaction{}, next: map[string]int{}}
+	for _, it := range c.Interactions {
+		k := commandKey(it.Command, it.Args)
+		p.byKey[k] = append(p.byKey[k], it)
+	}
+	return p
+}
+
+// GOOS is the platform the cassette was recorded on; local discovery runs
+// different commands per platform.
+func (p *Replayer) GOOS() string { return p.c.GOOS }
+
+func (p *Replayer) Run(ctx context.Context, name string, args ...string) Result {
+	k := commandKey(name, args)
+	logf(ctx, "op", "%s; calling exec with flags: %s", describeCommand(name, args), k)
+	p.mu.Lock()
+	list := p.byKey[k]
+	i := p.next[k]
+	if i < len(list)-1 {
+		p.next[k] = i + 1
+	}
+	p.mu.Unlock()
+	if len(list) == 0 {
+		logf(ctx, "op", "exec error: command not in cassette")
+		return Result{ExitCode: 127, Err: errors.New("command not in cassette: " + k)}
+	}
+	it := list[i]
+	res := Result{Stdout: it.Stdout, Stderr: it.Stderr, ExitCode: it.ExitCode, Duration: time.Duration(it.DurationMS) * time.Millisecond}
+	if it.Error != "" {
+		res.Err = replayedError(it.Error)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(res.Stdout), "\n") {
+		if strings.TrimSpace(line) != "" {
+			logf(ctx, "op", "logs: %s", line)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(res.Stderr), "\n") {
+		if strings.TrimSpace(line) != "" {
+			logf(ctx, "op", "stderr: %s", line)
+		}
+	}
+	if res.Err != nil {
+		logf(ctx, "op", "exec error: %v", res.Err)
+	}
+	return res
+}
+
+// LookPath answers from the recording. Tools the recorded run never looked
+// up are reported missing.
+func (p *Replayer) LookPath(file string) (string, error) {
+	if path := p.c.Paths[file]; path != "" {
+		return path, nil
+	}
+	return "", errors.New("not found")
+}
+
+// Replaying reports whether ex answers from a cassette. Work a check would do
+// in-process was never recorded, so replayed checks must not attempt it.
+func Replaying(ex Executor) bool {
+	_, ok := ex.(*Replayer)
+	return ok
+}
+
+// GOOS returns the platform ex runs commands for: the recording platform for
+// a replayed cassette, runtime.GOOS otherwise.
+func GOOS(ex Executor) string {
+	if p, ok := ex.(interface{ GOOS() string }); ok && p.GOOS() != "" {
+		return p.GOOS()
+	}
+	return runtime.GOOS
+}
+
+func commandKey(name string, args []string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
+
+// replayedError restores the context sentinels so timeout handling behaves
+// as it did in the recorded run.
+func replayedError(msg string) error {
+	switch msg {
+	case context.DeadlineExceeded.Error():
+		return context.DeadlineExceeded
+	case context.Canceled.Error():
+		return context.Canceled
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,73 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCassetteRoundTrip(t *testing.T) {
+	fake := &FakeExecutor{
+		Paths: map[string]bool{"ping": true},
+		Outputs: map[string]Result{
+			"ping -c 1 1.1.1.1": {Stdout: "1 packets transmitted, 1 packets received", Duration: 1500 * time.Millisecond},
+			"ping -c 1 9.9.9.9": {Stderr: "timeout", ExitCode: 2, Err: context.DeadlineExceeded},
+		},
+	}
+	rec := NewRecorder(fake)
+	if _, err := rec.LookPath("ping"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.LookPath("mtr"); err == nil {
+		t.Fatal("expected mtr to be missing")
+	}
+	rec.Run(context.Background(), "ping", "-c", "1", "1.1.1.1")
+	rec.Run(context.Background(), "ping", "-c", "1", "9.9.9.9")
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	rp, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rp.LookPath("ping"); err != nil {
+		t.Fatal("ping should be found on replay")
+	}
+	if _, err := rp.LookPath("mtr"); err == nil {
+		t.Fatal("mtr should stay missing on replay")
+	}
+	var logs []string
+	ctx := WithLogFunc(context.Background(), func(_, _, msg string) { logs = append(logs, msg) })
+	r := rp.Run(ctx, "ping", "-c", "1", "1.1.1.1")
+	if r.Stdout != "1 packets transmitted, 1 packets received" || r.Duration != 1500*time.Millisecond || r.Err != nil {
+		t.Fatalf("unexpected replay: %+v", r)
+	}
+	if len(logs) != 2 || logs[1] != "logs: 1 packets transmitted, 1 packets received" {
+		t.Fatalf("replay should log like a real run, got %q", logs)
+	}
+	r = rp.Run(ctx, "ping", "-c", "1", "9.9.9.9")
+	if !errors.Is(r.Err, context.DeadlineExceeded) || r.ExitCode != 2 || r.Stderr != "timeout" {
+		t.Fatalf("unexpected replayed failure: %+v", r)
+	}
+	if r := rp.Run(ctx, "ping", "-c", "1", "8.8.8.8"); r.Err == nil || r.ExitCode != 127 {
+		t.Fatalf("unrecorded commands should fail, got %+v", r)
+	}
+}
+
+func TestReplayerRepeatsLastInteraction(t *testing.T) {
+	rp := NewReplayer(Cassette{Version: CassetteVersion, Interactions: []Interaction{
+		{Command: "dig", Args: []string{"example.com"}, Stdout: "first"},
+		{Command: "dig", Args: []string{"example.com"}, Stdout: "second"},
+	}})
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, rp.Run(context.Background(), "dig", "example.com").Stdout)
+	}
+	if got[0] != "first" || got[1] != "second" || got[2] != "second" {
+		t.Fatalf("unexpected replay order: %v", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -57,12 +58,24 @@ func logf(ctx context.Context, op, format string, args ...any) {
 	fn(group, op, msg)
 }
 
+// Logf writes to the context log sink; in-process probes use it to match exec logging.
+func Logf(ctx context.Context, op, format string, args ...any) {
+	logf(ctx, op, format, args...)
+}
+
 func describeCommand(name string, args []string) string {
 	switch name {
 	case "netstat":
 		return "discovering routing/gateway information"
 	case "ifconfig":
 		return "collecting local interface information"
+	case "ip":
+		if len(args) > 1 && args[1] == "route" {
+			return "discovering routing/gateway information"
+		}
+		return "collecting local interface information"
+	case "cat":
+		return "reading kernel network state"
 	case "ping":
 		if len(args) > 0 {
 			return "measuring latency/loss to target"
@@ -76,10 +89,12 @@ func describeCommand(name string, args []string) string {
 		return "collecting path quality and hop loss"
 	case "traceroute":
 		return "collecting route hop path"
-	case "speedtest-cli":
+	case "speedtest-cli", "speedtest", "librespeed-cli":
 		return "running internet throughput test"
 	case "iperf3":
 		return "running controlled throughput test"
+	case "networkQuality":
+		return "running throughput and responsiveness test"
 	case "openssl":
 		return "reading TLS handshake metadata"
 	default:
@@ -126,9 +141,13 @@ func (RealExecutor) LookPath(file string) (string, error) { return exec.LookPath
 
 type FakeExecutor struct {
 	Outputs map[string]Result
-	Paths   map[string]bool
-	Delays  map[string]time.Duration
-	Calls   []string
+	// Sequences answers repeated commands in order, repeating the last
+	// result; a key listed here takes precedence over Outputs.
+	Sequences map[string][]Result
+	Paths     map[string]bool
+	Delays    map[string]time.Duration
+	Calls     []string
+	mu        sync.Mutex
 }
 
 func (f *FakeExecutor) key(name string, args ...string) string {
@@ -145,7 +164,9 @@ func (f *FakeExecutor) Run(ctx context.Context, name string, args ...string) Res
 
 func (f *FakeExecutor) run(ctx context.Context, name string, args ...string) Result {
 	k := f.key(name, args...)
+	f.mu.Lock()
 	f.Calls = append(f.Calls, k)
+	f.mu.Unlock()
 	logf(ctx, "op", "%s; calling exec with flags: %s", describeCommand(name, args), k)
 	if d, ok := f.Delays[k]; ok && d > 0 {
 		select {
@@ -155,7 +176,7 @@ func (f *FakeExecutor) run(ctx context.Context, name string, args ...string) Res
 			return Result{ExitCode: -1, Err: ctx.Err()}
 		}
 	}
-	if r, ok := f.Outputs[k]; ok {
+	if r, ok := f.result(k); ok {
 		for _, line := range strings.Split(strings.TrimSpace(r.Stdout), "\n") {
 			if strings.TrimSpace(line) != "" {
 				logf(ctx, "op", "logs: %s", line)
@@ -175,6 +196,19 @@ func (f *FakeExecutor) run(ctx context.Context, name string, args ...string) Res
 	return Result{ExitCode: 127, Err: errors.New("no fake output configured")}
 }
 
+func (f *FakeExecutor) result(k string) (Result, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if seq := f.Sequences[k]; len(seq) > 0 {
+		if len(seq) > 1 {
+			f.Sequences[k] = seq[1:]
+		}
+		return seq[0], true
+	}
+	r, ok := f.Outputs[k]
+	return r, ok
+}
+
 func (f *FakeExecutor) LookPath(file string) (string, error) {
 	if f.Paths == nil {
 		return "", errors.New("not found")
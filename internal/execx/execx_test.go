@@ -35,3 +35,17 @@ func TestHasTimeoutError(t *testing.T) {
 		t.Fatal("expected true")
 	}
 }
+
+func TestFakeExecutorSequences(t *testing.T) {
+	f := &FakeExecutor{
+		Outputs:   map[string]Result{"ping x": {Stdout: "outputs"}},
+		Sequences: map[string][]Result{"ping x": {{Stdout: "first"}, {Stdout: "second"}}},
+	}
+	var got []string
+	for range 3 {
+		got = append(got, f.Run(context.Background(), "ping", "x").Stdout)
+	}
+	if got[0] != "first" || got[1] != "second" || got[2] != "second" {
+		t.Fatalf("expected sequence then repeat of last, got %v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	RootCAs            *x509.CertPool
+	InsecureSkipVerify bool
+}
+
+// Result holds one request's timings and connection metadata. Timings are
+// cumulative from request start, matching curl's -w time_* variables.
+type Result struct {
+	StatusCode  int
+	Proto       string
+	DNS         time.Duration
+	Connect     time.Duration
+	TLS         time.Duration
+	TTFB        time.Duration
+	Total       time.Duration
+	ALPN        string
+	TLSVersion  string
+	CipherSuite string
+	PeerCerts   []*x509.Certificate
+}
+
+// Probe issues a single GET without following redirects and records every phase
+// of that one connection through httptrace.
+func Probe(ctx context.Context, rawURL string, opts Options) (Result, error) {
+	var res Result
+	var start, dnsDone, connectDone, tlsDone, firstByte time.Time
+	var state *tls.ConnectionState
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				connectDone = time.Now()
+			}
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			if err == nil {
+				tlsDone = time.Now()
+				state = &cs
+			}
+		},
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	tr := &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: opts.RootCAs, InsecureSkipVerify: opts.InsecureSkipVerify},
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}
+	defer tr.CloseIdleConnections()
+	client := &http.Client{
+		Transport:     tr,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, rawURL, nil)
+	if err != nil {
+		return res, err
+	}
+	start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	res.Total = time.Since(start)
+	if err != nil {
+		return res, err
+	}
+	res.StatusCode = resp.StatusCode
+	res.Proto = resp.Proto
+	res.DNS = since(start, dnsDone)
+	res.Connect = since(start, connectDone)
+	res.TLS = since(start, tlsDone)
+	res.TTFB = since(start, firstByte)
+	if state == nil {
+		state = resp.TLS
+	}
+	if state != nil {
+		res.ALPN = state.NegotiatedProtocol
+		res.TLSVersion = VersionName(state.Version)
+		res.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+		res.PeerCerts = state.PeerCertificates
+	}
+	return res, nil
+}
+
+// VersionName renders a TLS version the way openssl s_client prints it (TLSv1.3).
+func VersionName(v uint16) string {
+	return strings.Replace(tls.VersionName(v), "TLS ", "TLSv", 1)
+}
+
+func since(start, t time.Time) time.Duration {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Sub(start)
+}
@@ -0,0 +1,62 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeTLSServerHTTP2(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	res, err := Probe(context.Background(), srv.URL, Options{RootCAs: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || res.ALPN != "h2" || res.Proto != "HTTP/2.0" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.TLSVersion != "TLSv1.3" || res.CipherSuite == "" || len(res.PeerCerts) == 0 {
+		t.Fatalf("missing tls metadata: %+v", res)
+	}
+	if res.Connect <= 0 || res.TLS < res.Connect || res.TTFB < res.TLS || res.Total < res.TTFB {
+		t.Fatalf("timings are not cumulative: %+v", res)
+	}
+}
+
+func TestProbeRejectsUntrustedCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	if _, err := Probe(context.Background(), srv.URL, Options{}); err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+}
+
+func TestProbeDoesNotFollowRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+	res, err := Probe(context.Background(), srv.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusFound || res.TLSVersion != "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestVersionName(t *testing.T) {
+	if got := VersionName(tls.VersionTLS12); got != "TLSv1.2" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -14,19 +14,33 @@ const (
 const SchemaVersion = "v1"
 
 type Report struct {
-	SchemaVersion string                 `json:"schema_version"`
-	Timestamp     time.Time              `json:"timestamp"`
-	Host          string                 `json:"host"`
-	OS            string                 `json:"os"`
-	Version       string                 `json:"version"`
-	GitCommit     string                 `json:"git_commit,omitempty"`
-	RunID         string                 `json:"run_id,omitempty"`
-	Labels        map[string]string      `json:"labels,omitempty"`
-	Config        map[string]any         `json:"config"`
-	Checks        []CheckResult          `json:"checks"`
-	Summary       Summary                `json:"summary"`
-	Score         int                    `json:"score"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	SchemaVersion  string                 `json:"schema_version"`
+	Timestamp      time.Time              `json:"timestamp"`
+	Host           string                 `json:"host"`
+	OS             string                 `json:"os"`
+	Version        string                 `json:"version"`
+	GitCommit      string                 `json:"git_commit,omitempty"`
+	RunID          string                 `json:"run_id,omitempty"`
+	Labels         map[string]string      `json:"labels,omitempty"`
+	Config         map[string]any         `json:"config"`
+	Checks         []CheckResult          `json:"checks"`
+	Summary        Summary                `json:"summary"`
+	Score          int                    `json:"score"`
+	Scoring        *ScoringModel          `json:"scoring,omitempty"`
+	ScoreBreakdown *ScoreBreakdown        `json:"score_breakdown,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ScoringModel describes how check statuses become the 0-100 score. Groups
+// map to weighted categories; a category's value is the weighted mean of its
+// checks' status values.
+type ScoringModel struct {
+	Weights         map[string]float64 `json:"weights"`
+	Groups          map[string]string  `json:"groups"`
+	DefaultCategory string             `json:"default_category,omitempty"`
+	StatusValues    map[Status]float64 `json:"status_values"`
+	ExcludeSkips    bool               `json:"exclude_skips"`
+	CheckWeights    map[string]float64 `json:"check_weights,omitempty"`
 }
 
 type CheckResult struct {
@@ -38,6 +52,7 @@ type CheckResult struct {
 	Raw        string         `json:"raw,omitempty"`
 	DurationMS int64          `json:"duration_ms"`
 	Error      string         `json:"error,omitempty"`
+	BlockedBy  string         `json:"blocked_by,omitempty"`
 }
 
 type Summary struct {
@@ -65,6 +80,50 @@ type RunOptions struct {
 	CommandName string
 }
 
+// ScoreBreakdown splits the score into category and per-check points. Each
+// category is worth its normalized share of 100 points, divided between its
+// checks by check weight.
+type ScoreBreakdown struct {
+	Score      int             `json:"score"`
+	Categories []CategoryScore `json:"categories"`
+}
+
+type CategoryScore struct {
+	Category  string        `json:"category"`
+	Weight    float64       `json:"weight"`
+	MaxPoints float64       `json:"max_points"`
+	Points    float64       `json:"points"`
+	Lost      float64       `json:"lost"`
+	Checks    []CheckPoints `json:"checks"`
+}
+
+type CheckPoints struct {
+	ID        string  `json:"id"`
+	Group     string  `json:"group"`
+	Status    Status  `json:"status"`
+	Weight    float64 `json:"weight"`
+	MaxPoints float64 `json:"max_points"`
+	Points    float64 `json:"points"`
+	Lost      float64 `json:"lost"`
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// Category returns the scoring category of group, or "" when it is not scored.
+func (m ScoringModel) Category(group string) string {
+	if cat, ok := m.Groups[group]; ok {
+		return cat
+	}
+	return m.DefaultCategory
+}
+
+// CheckWeight is the weight of a check within its category; 1 unless overridden.
+func (m ScoringModel) CheckWeight(id string) float64 {
+	if w, ok := m.CheckWeights[id]; ok {
+		return w
+	}
+	return 1
+}
+
 func (s *Summary) Add(status Status) {
 	s.Total++
 	switch status {
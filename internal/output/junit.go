@@ -0,0 +1,191 @@
+package output
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"netcheck/internal/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders one testsuite per check group and one testcase per check.
+// Warn results fail the testcase only when strictWarn is set; otherwise they
+// pass with a system-out note.
+func WriteJUnit(w io.Writer, report model.Report, strictWarn bool) error {
+	byGroup := map[string][]model.CheckResult{}
+	for _, c := range report.Checks {
+		byGroup[c.Group] = append(byGroup[c.Group], c)
+	}
+	groups := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	root := junitSuites{Name: "netcheck"}
+	var totalMS int64
+	for _, g := range groups {
+		suite := junitSuite{Name: g, Hostname: report.Host, Properties: labelProperties(report.Labels)}
+		if !report.Timestamp.IsZero() {
+			suite.Timestamp = report.Timestamp.Format(time.RFC3339)
+		}
+		var suiteMS int64
+		for _, c := range byGroup[g] {
+			tc := junitCase{Name: c.ID, Classname: "netcheck." + g, Time: seconds(c.DurationMS), Properties: checkProperties(c)}
+			msg := c.Error
+			switch c.Status {
+			case model.StatusFail:
+				if msg == "" {
+					msg = "check failed"
+				}
+				tc.Failure = &junitMessage{Message: msg, Type: string(c.Status), Text: caseDetail(c)}
+				suite.Failures++
+			case model.StatusWarn:
+				if msg == "" {
+					msg = "check warned"
+				}
+				if strictWarn {
+					tc.Failure = &junitMessage{Message: msg, Type: string(c.Status), Text: caseDetail(c)}
+					suite.Failures++
+				} else {
+					tc.SystemOut = "warn: " + msg
+				}
+			case model.StatusSkip:
+				tc.Skipped = &junitMessage{Message: msg}
+				suite.Skipped++
+			}
+			suite.Tests++
+			suiteMS += c.DurationMS
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Time = seconds(suiteMS)
+		totalMS += suiteMS
+		root.Tests += suite.Tests
+		root.Failures += suite.Failures
+		root.Skipped += suite.Skipped
+		root.Suites = append(root.Suites, suite)
+	}
+	root.Time = seconds(totalMS)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func checkProperties(c model.CheckResult) []junitProperty {
+	var props []junitProperty
+	if c.Target != "" {
+		props = append(props, junitProperty{"target", c.Target})
+	}
+	props = append(props, junitProperty{"status", string(c.Status)})
+	if c.BlockedBy != "" {
+		props = append(props, junitProperty{"blocked_by", c.BlockedBy})
+	}
+	keys := make([]string, 0, len(c.Metrics))
+	for k := range c.Metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		props = append(props, junitProperty{"metric." + k, propertyValue(c.Metrics[k])})
+	}
+	return props
+}
+
+func labelProperties(labels map[string]string) []junitProperty {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	props := make([]junitProperty, 0, len(keys))
+	for _, k := range keys {
+		props = append(props, junitProperty{"label." + k, labels[k]})
+	}
+	return props
+}
+
+func caseDetail(c model.CheckResult) string {
+	var b strings.Builder
+	if c.Target != "" {
+		fmt.Fprintf(&b, "target: %s\n", c.Target)
+	}
+	if c.Error != "" {
+		fmt.Fprintf(&b, "error: %s\n", c.Error)
+	}
+	for _, p := range checkProperties(c) {
+		if strings.HasPrefix(p.Name, "metric.") {
+			fmt.Fprintf(&b, "%s: %s\n", strings.TrimPrefix(p.Name, "metric."), p.Value)
+		}
+	}
+	return b.String()
+}
+
+func propertyValue(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64, int, int64, bool:
+		return fmt.Sprint(t)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"netcheck/internal/eval"
 	"netcheck/internal/model"
 	"sort"
 	"strings"
@@ -46,8 +47,25 @@ func WriteTableWithOptions(w io.Writer, report model.Report, opts TableOptions)
 		}
 		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.ID, group, status, c.Target)
 	}
+	blocked := false
+	for _, c := range checks {
+		if c.BlockedBy == "" {
+			continue
+		}
+		if !blocked {
+			_, _ = fmt.Fprintln(tw, "\nBlocked Checks")
+			blocked = true
+		}
+		_, _ = fmt.Fprintf(tw, "%s\tblocked by\t%s\n", c.ID, strings.Join(BlockedChain(checks, c.ID), " <- "))
+	}
 	_, _ = fmt.Fprintf(tw, "\nSummary\tpass=%d warn=%d fail=%d skip=%d total=%d\t\t\n", report.Summary.Pass, report.Summary.Warn, report.Summary.Fail, report.Summary.Skip, report.Summary.Total)
 	_, _ = fmt.Fprintf(tw, "Score\t%d\t\t\n", report.Score)
+	if lost := lostPoints(report.ScoreBreakdown); len(lost) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nScore Deductions")
+		for _, cp := range lost {
+			_, _ = fmt.Fprintln(tw, lostLine(cp))
+		}
+	}
 	groupRows := buildGroupRows(report)
 	if len(groupRows) > 0 {
 		_, _ = fmt.Fprintln(tw, "\nGroup Summary\t\t\t")
@@ -64,6 +82,21 @@ func WriteTableWithOptions(w io.Writer, report model.Report, opts TableOptions)
 	return tw.Flush()
 }
 
+// BlockedChain follows blocked_by links from id to the root-cause check.
+func BlockedChain(checks []model.CheckResult, id string) []string {
+	byID := make(map[string]model.CheckResult, len(checks))
+	for _, c := range checks {
+		byID[c.ID] = c
+	}
+	var chain []string
+	seen := map[string]bool{id: true}
+	for cur := byID[id].BlockedBy; cur != "" && !seen[cur]; cur = byID[cur].BlockedBy {
+		chain = append(chain, cur)
+		seen[cur] = true
+	}
+	return chain
+}
+
 func TableString(report model.Report) (string, error) {
 	var b bytes.Buffer
 	if err := WriteTable(&b, report); err != nil {
@@ -96,6 +129,8 @@ func colorGroup(group string) string {
 		code = "33"
 	case "http":
 		code = "75"
+	case "tls":
+		code = "141"
 	case "path":
 		code = "208"
 	case "reachability":
@@ -118,6 +153,10 @@ func buildGroupRows(report model.Report) []groupRow {
 	for _, c := range report.Checks {
 		byGroup[c.Group] = append(byGroup[c.Group], c)
 	}
+	scoring := eval.DefaultScoring()
+	if report.Scoring != nil {
+		scoring = *report.Scoring
+	}
 	groups := make([]string, 0, len(byGroup))
 	for g := range byGroup {
 		groups = append(groups, g)
@@ -128,7 +167,7 @@ func buildGroupRows(report model.Report) []groupRow {
 		cs := byGroup[g]
 		out = append(out, groupRow{
 			Group:    g,
-			Score:    computeGroupScore(cs),
+			Score:    eval.GroupScore(cs, scoring),
 			Measured: measuredForGroup(g, cs),
 			Expected: expectedForGroup(g, report.Config),
 		})
@@ -136,24 +175,6 @@ func buildGroupRows(report model.Report) []groupRow {
 	return out
 }
 
-func computeGroupScore(cs []model.CheckResult) int {
-	if len(cs) == 0 {
-		return 0
-	}
-	var sum float64
-	for _, c := range cs {
-		switch c.Status {
-		case model.StatusPass:
-			sum += 1
-		case model.StatusWarn:
-			sum += 0.6
-		case model.StatusSkip:
-			sum += 0.5
-		}
-	}
-	return int(math.Round(sum / float64(len(cs)) * 100))
-}
-
 func measuredForGroup(group string, cs []model.CheckResult) string {
 	switch group {
 	case "bandwidth":
@@ -165,6 +186,9 @@ func measuredForGroup(group string, cs []model.CheckResult) string {
 		if !math.IsNaN(dl) {
 			return fmt.Sprintf("dl=%.1fMbps", dl)
 		}
+		if !math.IsNaN(ul) {
+			return fmt.Sprintf("ul=%.1fMbps", ul)
+		}
 	case "dns":
 		q := metricAvg(cs, "query_ms")
 		if !math.IsNaN(q) {
@@ -199,7 +223,18 @@ func measuredForGroup(group string, cs []model.CheckResult) string {
 	case "bufferbloat":
 		d := metricAvg(cs, "delta_ms")
 		if !math.IsNaN(d) {
-			return fmt.Sprintf("delta=%.1fms", d)
+			// Grade the averaged delta rather than one check's grade.
+			return fmt.Sprintf("delta=%.1fms grade=%s", d, eval.BufferbloatGrade(d))
+		}
+	case "responsiveness":
+		r := metricMin(cs, "rpm")
+		if !math.IsNaN(r) {
+			return fmt.Sprintf("rpm=%.0f", r)
+		}
+	case "tls":
+		d := metricMin(cs, "days_until_expiry")
+		if !math.IsNaN(d) {
+			return fmt.Sprintf("min_expiry=%.0fd", d)
 		}
 	}
 	return "-"
@@ -237,6 +272,10 @@ func expectedForGroup(group string, cfg map[string]any) string {
 		return fmt.Sprintf("near_loss<%.1f%%", cfgFloat(cfg, "thresholds", "loss_pass_max"))
 	case "bufferbloat":
 		return fmt.Sprintf("delta<%.0fms", cfgFloat(cfg, "thresholds", "loaded_latency_pass_delta_ms"))
+	case "tls":
+		return fmt.Sprintf("expiry>=%.0fd warn>=%.0fd", cfgFloat(cfg, "thresholds", "tls_expiry_pass_days"), cfgFloat(cfg, "thresholds", "tls_expiry_warn_days"))
+	case "responsiveness":
+		return fmt.Sprintf("rpm>=%.0f warn>=%.0f", cfgFloat(cfg, "thresholds", "rpm_pass_min"), cfgFloat(cfg, "thresholds", "rpm_warn_min"))
 	default:
 		return "-"
 	}
@@ -272,6 +311,16 @@ func metricAvg(cs []model.CheckResult, key string) float64 {
 	return sum / float64(n)
 }
 
+func metricMin(cs []model.CheckResult, key string) float64 {
+	out := math.NaN()
+	for _, c := range cs {
+		if v, ok := c.Metrics[key].(float64); ok && (math.IsNaN(out) || v < out) {
+			out = v
+		}
+	}
+	return out
+}
+
 func cfgFloat(cfg map[string]any, path ...string) float64 {
 	var cur any = cfg
 	for _, p := range path {
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"netcheck/internal/eval"
 	"netcheck/internal/model"
 )
 
@@ -138,3 +139,117 @@ func TestPathMeasuredUsesTracerouteMetrics(t *testing.T) {
 		t.Fatalf("expected traceroute-style path metrics, got: %s", s)
 	}
 }
+
+func TestBufferbloatGradeFollowsAveragedDelta(t *testing.T) {
+	cs := []model.CheckResult{
+		{Group: "bufferbloat", Metrics: map[string]any{"delta_ms": 10.0, "grade": "A"}},
+		{Group: "bufferbloat", Metrics: map[string]any{"delta_ms": 70.0, "grade": "C"}},
+	}
+	if got := measuredForGroup("bufferbloat", cs); got != "delta=40.0ms grade=B" {
+		t.Fatalf("unexpected bufferbloat summary %q", got)
+	}
+}
+
+func TestTableShowsBlockedChain(t *testing.T) {
+	r := sampleReport()
+	r.Checks = append(r.Checks,
+		model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusFail},
+		model.CheckResult{ID: "reachability.1.1.1.1", Group: "reachability", Status: model.StatusSkip, BlockedBy: "local.gateway"},
+		model.CheckResult{ID: "dns.google.com@1.1.1.1", Group: "dns", Status: model.StatusSkip, BlockedBy: "reachability.1.1.1.1"},
+	)
+	s, err := TableString(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(s, "Blocked Checks") || !strings.Contains(s, "blocked by  reachability.1.1.1.1 <- local.gateway") {
+		t.Fatalf("expected blocked chain in table, got: %s", s)
+	}
+}
+
+func breakdownReport() model.Report {
+	r := sampleReport()
+	r.Checks = append(r.Checks, model.CheckResult{ID: "reachability.8.8.8.8", Group: "reachability", Status: model.StatusWarn, Metrics: map[string]any{"rtt_p95_ms": float64(62)}})
+	limits := func(group string) []eval.Limit {
+		return []eval.Limit{{Metric: "rtt_p95_ms", Pass: 40, LowerIsBetter: true}}
+	}
+	b := eval.Breakdown(r.Checks, eval.DefaultScoring(), limits)
+	r.Score = b.Score
+	r.ScoreBreakdown = &b
+	return r
+}
+
+func TestTableListsScoreDeductions(t *testing.T) {
+	s, err := TableString(breakdownReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Score Deductions\nlost 21.5 points: reachability.8.8.8.8 warn (rtt_p95_ms 62 > 40)\nlost 12.3 points: bandwidth.speedtest warn\n"
+	if !strings.Contains(s, want) {
+		t.Fatalf("expected deductions worst first, got: %s", s)
+	}
+}
+
+func TestWriteScoreExplanation(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteScoreExplanation(&b, breakdownReport()); err != nil {
+		t.Fatal(err)
+	}
+	s := b.String()
+	for _, want := range []string{"Score  66", "reliability  35      32.3    53.9  1", "lost 21.5 points: reachability.8.8.8.8 warn"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expected %q in explanation, got:\n%s", want, s)
+		}
+	}
+	var none bytes.Buffer
+	if err := WriteScoreExplanation(&none, sampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(none.String(), "no points lost") {
+		t.Fatalf("unexpected explanation: %s", none.String())
+	}
+}
+
+func junitReport() model.Report {
+	r := sampleReport()
+	r.Labels = map[string]string{"site": "edge-1"}
+	r.Checks = append(r.Checks,
+		model.CheckResult{ID: "reachability.1.1.1.1", Group: "reachability", Target: "1.1.1.1", Status: model.StatusFail, DurationMS: 9120, Error: "100% packet loss", Metrics: map[string]any{"loss_pct": float64(100)}},
+		model.CheckResult{ID: "dns.example.com", Group: "dns", Status: model.StatusSkip, Error: "blocked by reachability.1.1.1.1", BlockedBy: "reachability.1.1.1.1"},
+	)
+	r.Checks[0].Error = "download below plan"
+	r.Checks[1].DurationMS = 22
+	return r
+}
+
+func TestJUnitGolden(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJUnit(&b, junitReport(), false); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	goldPath := filepath.Join("..", "..", "testdata", "golden", "report.junit.xml")
+	if os.Getenv("UPDATE_GOLDEN") == "1" {
+		_ = os.WriteFile(goldPath, []byte(got), 0o644)
+	}
+	exp, err := os.ReadFile(goldPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(got) != strings.TrimSpace(string(exp)) {
+		t.Fatalf("junit mismatch\n---got---\n%s\n---exp---\n%s", got, string(exp))
+	}
+}
+
+func TestJUnitStrictWarnFails(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJUnit(&b, junitReport(), true); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	if !strings.Contains(got, `<testsuites name="netcheck" tests="4" failures="2" skipped="1"`) {
+		t.Fatalf("unexpected totals:\n%s", got)
+	}
+	if !strings.Contains(got, `<failure message="download below plan" type="warn">`) || strings.Contains(got, "<system-out>") {
+		t.Fatalf("warn should be a failure in strict mode:\n%s", got)
+	}
+}
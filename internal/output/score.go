@@ -0,0 +1,69 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"netcheck/internal/model"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// WriteScoreExplanation prints the per-category score and the checks that
+// lost points, worst first.
+func WriteScoreExplanation(w io.Writer, report model.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Score\t%d\t\t\t\n", report.Score)
+	if b := report.ScoreBreakdown; b != nil && len(b.Categories) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nCATEGORY\tWEIGHT\tPOINTS\tMAX\tCHECKS")
+		_, _ = fmt.Fprintln(tw, "--------\t------\t------\t---\t------")
+		for _, c := range b.Categories {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", c.Category, points(c.Weight), points(c.Points), points(c.MaxPoints), len(c.Checks))
+		}
+	}
+	lost := lostPoints(report.ScoreBreakdown)
+	if len(lost) == 0 {
+		_, _ = fmt.Fprintln(tw, "\nno points lost")
+		return tw.Flush()
+	}
+	_, _ = fmt.Fprintln(tw)
+	for _, cp := range lost {
+		_, _ = fmt.Fprintln(tw, lostLine(cp))
+	}
+	return tw.Flush()
+}
+
+// lostPoints lists the checks that lost points, most points first.
+func lostPoints(b *model.ScoreBreakdown) []model.CheckPoints {
+	if b == nil {
+		return nil
+	}
+	var out []model.CheckPoints
+	for _, c := range b.Categories {
+		for _, cp := range c.Checks {
+			if cp.Lost > 0 {
+				out = append(out, cp)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Lost != out[j].Lost {
+			return out[i].Lost > out[j].Lost
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func lostLine(cp model.CheckPoints) string {
+	s := fmt.Sprintf("lost %s points: %s %s", points(cp.Lost), cp.ID, cp.Status)
+	if cp.Reason != "" {
+		s += " (" + cp.Reason + ")"
+	}
+	return s
+}
+
+func points(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
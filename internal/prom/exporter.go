@@ -0,0 +1,123 @@
+package prom
+
+import (
+	"bytes"
+	"net/http"
+	"netcheck/internal/model"
+	"sort"
+	"sync"
+)
+
+// DurationBuckets are the check_duration_seconds histogram upper bounds.
+var DurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+var statuses = []model.Status{model.StatusPass, model.StatusWarn, model.StatusFail, model.StatusSkip}
+
+type series struct {
+	check   model.CheckResult
+	counts  map[model.Status]float64
+	buckets []float64
+	sum     float64
+	count   float64
+}
+
+// Exporter keeps the latest report plus cumulative per-check counters and
+// serves them on /metrics.
+type Exporter struct {
+	mu     sync.Mutex
+	last   *model.Report
+	runs   float64
+	byID   map[string]*series
+	consts []Label
+}
+
+func NewExporter() *Exporter {
+	return &Exporter{byID: map[string]*series{}}
+}
+
+// Observe records one completed run. The run may cover only some checks, as
+// when groups run on different schedules; checks it leaves out keep the
+// gauges of their latest result.
+func (e *Exporter) Observe(r model.Report) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runs++
+	e.consts = ConstLabels(r.Labels)
+	for _, c := range r.Checks {
+		s, ok := e.byID[c.ID]
+		if !ok {
+			s = &series{counts: map[model.Status]float64{}, buckets: make([]float64, len(DurationBuckets))}
+			e.byID[c.ID] = s
+		}
+		s.check = c
+		s.counts[c.Status]++
+		sec := float64(c.DurationMS) / 1000
+		for i, ub := range DurationBuckets {
+			if sec <= ub {
+				s.buckets[i]++
+			}
+		}
+		s.sum += sec
+		s.count++
+	}
+	ids := make([]string, 0, len(e.byID))
+	for id := range e.byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	r.Checks = make([]model.CheckResult, 0, len(ids))
+	for _, id := range ids {
+		r.Checks = append(r.Checks, e.byID[id].check)
+	}
+	e.last = &r
+}
+
+// Families returns the exporter state as metric families.
+func (e *Exporter) Families() []Family {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fams := []Family{{Name: "netcheck_runs_total", Help: "Completed check runs.", Type: "counter", Samples: []Sample{{Labels: e.consts, Value: e.runs}}}}
+	if e.last == nil {
+		return fams
+	}
+	fams = append(fams, ReportFamilies(*e.last, e.consts)...)
+	ids := make([]string, 0, len(e.byID))
+	for id := range e.byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	results := Family{Name: "netcheck_check_results_total", Help: "Check results by status since the exporter started.", Type: "counter"}
+	hist := Family{Name: "netcheck_check_duration_seconds", Help: "Check run duration.", Type: "histogram"}
+	for _, id := range ids {
+		s := e.byID[id]
+		labels := CheckLabels(s.check, e.consts)
+		for _, st := range statuses {
+			results.Samples = append(results.Samples, Sample{Labels: withLabel(labels, "status", string(st)), Value: s.counts[st]})
+		}
+		for i, ub := range DurationBuckets {
+			hist.Samples = append(hist.Samples, Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", formatValue(ub)), Value: s.buckets[i]})
+		}
+		hist.Samples = append(hist.Samples,
+			Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", "+Inf"), Value: s.count},
+			Sample{Suffix: "_sum", Labels: labels, Value: s.sum},
+			Sample{Suffix: "_count", Labels: labels, Value: s.count},
+		)
+	}
+	return append(fams, results, hist)
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var b bytes.Buffer
+	if err := Write(&b, e.Families()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(b.Bytes())
+}
+
+func withLabel(labels []Label, name, value string) []Label {
+	out := make([]Label, 0, len(labels)+1)
+	out = append(out, labels...)
+	return append(out, Label{name, value})
+}
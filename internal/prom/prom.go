@@ -0,0 +1,194 @@
+package prom
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"netcheck/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Label is one name="value" pair; order is preserved in the output.
+type Label struct {
+	Name  string
+	Value string
+}
+
+type Sample struct {
+	Suffix string // appended to the family name, e.g. "_bucket"
+	Labels []Label
+	Value  float64
+}
+
+// Family is one metric name with its HELP/TYPE header and samples.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Write renders families in the Prometheus text exposition format, sorted by name.
+func Write(w io.Writer, fams []Family) error {
+	sort.SliceStable(fams, func(i, j int) bool { return fams[i].Name < fams[j].Name })
+	var b strings.Builder
+	for _, f := range fams {
+		if len(f.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			b.WriteString(f.Name + s.Suffix)
+			if len(s.Labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", l.Name, escapeLabel(l.Value))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.Value))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CheckLabels identifies a check series; constant labels from the report follow.
+func CheckLabels(c model.CheckResult, constant []Label) []Label {
+	out := []Label{{"id", c.ID}, {"group", c.Group}, {"target", c.Target}}
+	return append(out, constant...)
+}
+
+// ConstLabels turns Report.Labels into sorted, sanitized constant labels.
+// Keys that collide with the per-check labels are skipped.
+func ConstLabels(labels map[string]string) []Label {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]Label, 0, len(keys))
+	for _, k := range keys {
+		name := SanitizeName(k)
+		switch name {
+		case "id", "group", "target", "status", "le":
+			continue
+		}
+		out = append(out, Label{name, labels[k]})
+	}
+	return out
+}
+
+// MetricFamilies exposes every numeric or boolean check metric as a gauge named
+// netcheck_<group>_<metric>, converted to base units (_ms to _seconds,
+// _pct to _ratio, _mbps to _bits_per_second).
+func MetricFamilies(r model.Report, constant []Label) []Family {
+	byName := map[string]*Family{}
+	var order []string
+	for _, c := range r.Checks {
+		keys := make([]string, 0, len(c.Metrics))
+		for k := range c.Metrics {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, ok := numeric(c.Metrics[k])
+			if !ok {
+				continue
+			}
+			name, help, value := metricName(c.Group, k, v)
+			f, ok := byName[name]
+			if !ok {
+				f = &Family{Name: name, Help: help, Type: "gauge"}
+				byName[name] = f
+				order = append(order, name)
+			}
+			f.Samples = append(f.Samples, Sample{Labels: CheckLabels(c, constant), Value: value})
+		}
+	}
+	out := make([]Family, 0, len(order))
+	for _, n := range order {
+		out = append(out, *byName[n])
+	}
+	return out
+}
+
+func metricName(group, key string, v float64) (string, string, float64) {
+	base := "netcheck_" + SanitizeName(group) + "_"
+	help := fmt.Sprintf("Check metric %s reported by the %s group.", key, group)
+	switch {
+	case strings.HasSuffix(key, "_ms"):
+		return base + SanitizeName(strings.TrimSuffix(key, "_ms")) + "_seconds", help, v / 1000
+	case strings.HasSuffix(key, "_sec"):
+		return base + SanitizeName(strings.TrimSuffix(key, "_sec")) + "_seconds", help, v
+	case strings.HasSuffix(key, "_pct"):
+		return base + SanitizeName(strings.TrimSuffix(key, "_pct")) + "_ratio", help, v / 100
+	case strings.HasSuffix(key, "_mbps"):
+		return base + SanitizeName(strings.TrimSuffix(key, "_mbps")) + "_bits_per_second", help, v * 1e6
+	}
+	return base + SanitizeName(key), help, v
+}
+
+// SanitizeName maps s onto the [a-zA-Z_][a-zA-Z0-9_]* metric/label name alphabet.
+func SanitizeName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func numeric(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, !math.IsNaN(n) && !math.IsInf(n, 0)
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,117 @@
+package prom
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"netcheck/internal/model"
+	"strings"
+	"testing"
+)
+
+func sampleReport(status model.Status, durationMS int64) model.Report {
+	return model.Report{
+		Score:  87,
+		Labels: map[string]string{"site": "home", "id": "ignored"},
+		Checks: []model.CheckResult{
+			{ID: "dns.example.com", Group: "dns", Target: "example.com", Status: status, DurationMS: durationMS,
+				Metrics: map[string]any{"query_ms": 12.5, "rcode": "NOERROR", "answer_count": 2, "truncated": false}},
+			{ID: "bandwidth.speedtest", Group: "bandwidth", Status: model.StatusPass, DurationMS: 20000,
+				Metrics: map[string]any{"download_mbps": 100.0, "loss_pct": 0.5}},
+		},
+	}
+}
+
+func TestWriteEscapesAndSorts(t *testing.T) {
+	var b bytes.Buffer
+	err := Write(&b, []Family{
+		{Name: "b_total", Help: "B.", Type: "counter", Samples: []Sample{{Labels: []Label{{"x", "a\"b\\c\nd"}}, Value: 1}}},
+		{Name: "a", Help: "A.", Type: "gauge", Samples: []Sample{{Value: 0.25}}},
+		{Name: "empty", Help: "none", Type: "gauge"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP a A.\n# TYPE a gauge\na 0.25\n# HELP b_total B.\n# TYPE b_total counter\nb_total{x=\"a\\\"b\\\\c\\nd\"} 1\n"
+	if b.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestExporterHandler(t *testing.T) {
+	e := NewExporter()
+	e.Observe(sampleReport(model.StatusPass, 40))
+	e.Observe(sampleReport(model.StatusFail, 3000))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	dns := `id="dns.example.com",group="dns",target="example.com",site="home"`
+	for _, want := range []string{
+		"netcheck_runs_total{site=\"home\"} 2\n",
+		"netcheck_score{site=\"home\"} 87\n",
+		"# TYPE netcheck_dns_query_seconds gauge\n",
+		"netcheck_dns_query_seconds{" + dns + "} 0.0125\n",
+		"netcheck_dns_answer_count{" + dns + "} 2\n",
+		"netcheck_dns_truncated{" + dns + "} 0\n",
+		"netcheck_bandwidth_download_bits_per_second{id=\"bandwidth.speedtest\",group=\"bandwidth\",target=\"\",site=\"home\"} 1e+08\n",
+		"netcheck_bandwidth_loss_ratio{id=\"bandwidth.speedtest\",group=\"bandwidth\",target=\"\",site=\"home\"} 0.005\n",
+		"netcheck_check_results_total{" + dns + ",status=\"pass\"} 1\n",
+		"netcheck_check_results_total{" + dns + ",status=\"fail\"} 1\n",
+		"netcheck_check_results_total{" + dns + ",status=\"skip\"} 0\n",
+		"# TYPE netcheck_check_duration_seconds histogram\n",
+		"netcheck_check_duration_seconds_bucket{" + dns + ",le=\"0.05\"} 1\n",
+		"netcheck_check_duration_seconds_bucket{" + dns + ",le=\"5\"} 2\n",
+		"netcheck_check_duration_seconds_bucket{" + dns + ",le=\"+Inf\"} 2\n",
+		"netcheck_check_duration_seconds_sum{" + dns + "} 3.04\n",
+		"netcheck_check_duration_seconds_count{" + dns + "} 2\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "rcode") {
+		t.Fatal("string metrics must not be exported")
+	}
+}
+
+func TestExporterKeepsChecksMissingFromAWindow(t *testing.T) {
+	e := NewExporter()
+	e.Observe(sampleReport(model.StatusPass, 40))
+	window := sampleReport(model.StatusFail, 40)
+	window.Checks = window.Checks[:1]
+	e.Observe(window)
+	var b bytes.Buffer
+	if err := Write(&b, e.Families()); err != nil {
+		t.Fatal(err)
+	}
+	speedtest := `id="bandwidth.speedtest",group="bandwidth",target="",site="home"`
+	for _, want := range []string{
+		"netcheck_bandwidth_download_bits_per_second{" + speedtest + "} 1e+08\n",
+		"netcheck_check_status{" + speedtest + ",status=\"pass\"} 1\n",
+		"netcheck_check_results_total{" + speedtest + ",status=\"pass\"} 1\n",
+		"netcheck_check_status{id=\"dns.example.com\",group=\"dns\",target=\"example.com\",site=\"home\",status=\"fail\"} 1\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("missing %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestExporterBeforeFirstRun(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, NewExporter().Families()); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "# HELP netcheck_runs_total Completed check runs.\n# TYPE netcheck_runs_total counter\nnetcheck_runs_total 0\n" {
+		t.Fatalf("unexpected output:\n%s", b.String())
+	}
+}
@@ -0,0 +1,57 @@
+package prom
+
+import (
+	"bytes"
+	"netcheck/internal/model"
+	"os"
+	"path/filepath"
+)
+
+// ReportFamilies renders one report: metric gauges, a status enum gauge per
+// check, the score and the run timestamp.
+func ReportFamilies(r model.Report, constant []Label) []Family {
+	status := Family{Name: "netcheck_check_status", Help: "Check status of the latest run; 1 for the current status, 0 otherwise.", Type: "gauge"}
+	for _, c := range r.Checks {
+		labels := CheckLabels(c, constant)
+		for _, st := range statuses {
+			v := 0.0
+			if c.Status == st {
+				v = 1
+			}
+			status.Samples = append(status.Samples, Sample{Labels: withLabel(labels, "status", string(st)), Value: v})
+		}
+	}
+	fams := []Family{
+		status,
+		{Name: "netcheck_score", Help: "Overall score of the latest run (0-100).", Type: "gauge", Samples: []Sample{{Labels: constant, Value: float64(r.Score)}}},
+		{Name: "netcheck_last_run_timestamp_seconds", Help: "Unix time the latest run started.", Type: "gauge", Samples: []Sample{{Labels: constant, Value: float64(r.Timestamp.UnixMilli()) / 1000}}},
+	}
+	return append(fams, MetricFamilies(r, constant)...)
+}
+
+// WriteFileAtomic writes the report to path through a temp file in the same
+// directory and a rename, so a textfile collector never reads a partial file.
+func WriteFileAtomic(path string, r model.Report) error {
+	var b bytes.Buffer
+	if err := Write(&b, ReportFamilies(r, ConstLabels(r.Labels))); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
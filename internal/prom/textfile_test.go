@@ -0,0 +1,49 @@
+package prom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"netcheck/internal/model"
+)
+
+func TestWriteFileAtomicGolden(t *testing.T) {
+	r := sampleReport(model.StatusWarn, 40)
+	r.Timestamp = time.Unix(1700000000, 500000000).UTC()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "netcheck.prom")
+	if err := os.WriteFile(path, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goldPath := filepath.Join("..", "..", "testdata", "golden", "report.prom")
+	if os.Getenv("UPDATE_GOLDEN") == "1" {
+		_ = os.WriteFile(goldPath, got, 0o644)
+	}
+	exp, err := os.ReadFile(goldPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(got)) != strings.TrimSpace(string(exp)) {
+		t.Fatalf("prom mismatch\n---got---\n%s\n---exp---\n%s", got, exp)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temp file left behind: %v", entries)
+	}
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	if err := WriteFileAtomic(filepath.Join(t.TempDir(), "nope", "netcheck.prom"), sampleReport(model.StatusPass, 1)); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
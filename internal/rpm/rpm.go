@@ -0,0 +1,304 @@
+// Package rpm measures responsiveness under working conditions, following the
+// IETF responsiveness draft: it saturates the link with parallel HTTP/2
+// downloads and uploads and reports Round-trips Per Minute.
+package rpm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Options struct {
+	// ConfigURL serves the draft's /config document.
+	ConfigURL string
+	Duration  time.Duration
+	// Connections is the number of load-generating connections per direction.
+	Connections   int
+	ProbeInterval time.Duration
+	RootCAs       *x509.CertPool
+}
+
+// Result holds trimmed means of each probe kind. Self probes reuse the
+// load-generating connections; foreign probes open a new connection each.
+type Result struct {
+	RPM           float64
+	SelfRTT       time.Duration
+	ForeignTCP    time.Duration
+	ForeignTLS    time.Duration
+	ForeignHTTP   time.Duration
+	SelfProbes    int
+	ForeignProbes int
+	DownloadMbps  float64
+	UploadMbps    float64
+	Proto         string
+}
+
+type samples struct {
+	mu                      sync.Mutex
+	self, tcp, tls, foreign []time.Duration
+}
+
+// Measure loads the link for opts.Duration while probing every
+// opts.ProbeInterval, then combines the probes as
+// 60 / (1/6·(tcp + tls + http foreign) + 1/2·http self) seconds.
+func Measure(ctx context.Context, opts Options) (Result, error) {
+	var res Result
+	if opts.Connections < 1 {
+		opts.Connections = 4
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = 100 * time.Millisecond
+	}
+	cfg, err := fetchConfig(ctx, opts)
+	if err != nil {
+		return res, err
+	}
+	lctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	var down, up atomic.Int64
+	var wg sync.WaitGroup
+	loads := make([]*http.Transport, opts.Connections)
+	ready := make(chan string, opts.Connections)
+	for i := range loads {
+		loads[i] = newTransport(opts)
+		defer loads[i].CloseIdleConnections()
+		wg.Add(2)
+		go func(tr *http.Transport) {
+			defer wg.Done()
+			download(lctx, tr, cfg.URLs.LargeDownloadURL, &down, ready)
+		}(loads[i])
+		go func() {
+			defer wg.Done()
+			upload(lctx, newTransport(opts), cfg.URLs.UploadURL, &up)
+		}()
+	}
+	start := time.Now()
+	for range loads {
+		select {
+		case proto := <-ready:
+			if proto == "" {
+				cancel()
+				wg.Wait()
+				return res, errors.New("load-generating download failed")
+			}
+			res.Proto = proto
+		case <-lctx.Done():
+			wg.Wait()
+			return res, fmt.Errorf("load did not start: %w", lctx.Err())
+		}
+	}
+
+	var s samples
+	var probes sync.WaitGroup
+	tick := time.NewTicker(opts.ProbeInterval)
+	defer tick.Stop()
+	for n := 0; lctx.Err() == nil; n++ {
+		probes.Add(2)
+		go func(tr *http.Transport) {
+			defer probes.Done()
+			s.selfProbe(lctx, tr, cfg.URLs.SmallDownloadURL)
+		}(loads[n%len(loads)])
+		go func() {
+			defer probes.Done()
+			s.foreignProbe(lctx, opts, cfg.URLs.SmallDownloadURL)
+		}()
+		select {
+		case <-tick.C:
+		case <-lctx.Done():
+		}
+	}
+	probes.Wait()
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+	res.DownloadMbps = float64(down.Load()) * 8 / elapsed / 1e6
+	res.UploadMbps = float64(up.Load()) * 8 / elapsed / 1e6
+	res.SelfProbes = len(s.self)
+	res.ForeignProbes = len(s.foreign)
+	if res.SelfProbes == 0 || res.ForeignProbes == 0 {
+		return res, errors.New("no probes completed under load")
+	}
+	res.SelfRTT = trimmedMean(s.self)
+	res.ForeignTCP = trimmedMean(s.tcp)
+	res.ForeignTLS = trimmedMean(s.tls)
+	res.ForeignHTTP = trimmedMean(s.foreign)
+	denom := (res.ForeignTCP+res.ForeignTLS+res.ForeignHTTP).Seconds()/6 + res.SelfRTT.Seconds()/2
+	if denom > 0 {
+		res.RPM = math.Round(60 / denom)
+	}
+	return res, nil
+}
+
+func fetchConfig(ctx context.Context, opts Options) (Config, error) {
+	var c Config
+	tr := newTransport(opts)
+	defer tr.CloseIdleConnections()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, opts.ConfigURL, nil)
+	if err != nil {
+		return c, err
+	}
+	resp, err := (&http.Client{Transport: tr, Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return c, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c, fmt.Errorf("config: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+		return c, fmt.Errorf("config: %w", err)
+	}
+	if c.URLs.SmallDownloadURL == "" || c.URLs.LargeDownloadURL == "" || c.URLs.UploadURL == "" {
+		return c, errors.New("config: missing small, large or upload url")
+	}
+	return c, nil
+}
+
+// newTransport speaks HTTP/2 only: over TLS for https and with prior
+// knowledge for http, so every load stream shares its connection with the
+// self probes sent through the same transport.
+func newTransport(opts Options) *http.Transport {
+	p := new(http.Protocols)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(true)
+	return &http.Transport{
+		Protocols:       p,
+		TLSClientConfig: &tls.Config{RootCAs: opts.RootCAs},
+	}
+}
+
+func download(ctx context.Context, tr *http.Transport, url string, n *atomic.Int64, ready chan<- string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		ready <- ""
+		return
+	}
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		ready <- ""
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		ready <- ""
+		return
+	}
+	ready <- resp.Proto
+	buf := make([]byte, largeChunk)
+	for {
+		m, err := resp.Body.Read(buf)
+		n.Add(int64(m))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func upload(ctx context.Context, tr *http.Transport, url string, n *atomic.Int64) {
+	defer tr.CloseIdleConnections()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &endless{ctx: ctx, n: n})
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// endless is an upload body of zeros that ends when ctx does.
+type endless struct {
+	ctx context.Context
+	n   *atomic.Int64
+}
+
+func (e *endless) Read(p []byte) (int, error) {
+	if err := e.ctx.Err(); err != nil {
+		return 0, io.EOF
+	}
+	clear(p)
+	e.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// selfProbe times a small GET on a load-generating connection.
+func (s *samples) selfProbe(ctx context.Context, tr *http.Transport, url string) {
+	reused := false
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+	start := time.Now()
+	if !get(httptrace.WithClientTrace(ctx, trace), tr, url) || !reused {
+		return
+	}
+	d := time.Since(start)
+	s.mu.Lock()
+	s.self = append(s.self, d)
+	s.mu.Unlock()
+}
+
+// foreignProbe times the TCP handshake, TLS handshake and a small GET on a
+// fresh connection.
+func (s *samples) foreignProbe(ctx context.Context, opts Options, url string) {
+	var connStart, connDone, tlsStart, tlsDone, wrote, first time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart:         func(_, _ string) { connStart = time.Now() },
+		ConnectDone:          func(_, _ string, _ error) { connDone = time.Now() },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() { first = time.Now() },
+	}
+	tr := newTransport(opts)
+	defer tr.CloseIdleConnections()
+	if !get(httptrace.WithClientTrace(ctx, trace), tr, url) || connDone.IsZero() || first.IsZero() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tcp = append(s.tcp, connDone.Sub(connStart))
+	if !tlsStart.IsZero() {
+		s.tls = append(s.tls, tlsDone.Sub(tlsStart))
+	}
+	s.foreign = append(s.foreign, first.Sub(wrote))
+}
+
+func get(ctx context.Context, tr *http.Transport, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return false
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return err == nil && resp.StatusCode == http.StatusOK
+}
+
+// trimmedMean drops samples above the 95th percentile before averaging.
+func trimmedMean(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	cp := append([]time.Duration(nil), ds...)
+	sort.Slice(cp, func(i, j int) bool { return cp[i] < cp[j] })
+	keep := int(math.Ceil(float64(len(cp)) * 0.95))
+	var sum time.Duration
+	for _, d := range cp[:keep] {
+		sum += d
+	}
+	return sum / time.Duration(keep)
+}
@@ -0,0 +1,71 @@
+package rpm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMeasureAgainstLocalServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(Handler())
+	srv.Config.Protocols = serverProtocols()
+	srv.Start()
+	defer srv.Close()
+	res, err := Measure(context.Background(), Options{
+		ConfigURL:     srv.URL + "/config",
+		Duration:      700 * time.Millisecond,
+		Connections:   2,
+		ProbeInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Proto != "HTTP/2.0" {
+		t.Fatalf("expected HTTP/2 load connections, got %q", res.Proto)
+	}
+	if res.RPM <= 0 || res.SelfProbes == 0 || res.ForeignProbes == 0 || res.ForeignTCP <= 0 {
+		t.Fatalf("expected probes and rpm, got %+v", res)
+	}
+	if res.DownloadMbps <= 0 || res.UploadMbps <= 0 {
+		t.Fatalf("expected load in both directions, got %+v", res)
+	}
+}
+
+func TestMeasureFailsWithoutLoad(t *testing.T) {
+	h := Handler()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+	srv.Config.Protocols = serverProtocols()
+	srv.Start()
+	defer srv.Close()
+	_, err := Measure(context.Background(), Options{ConfigURL: srv.URL + "/config", Duration: time.Second, Connections: 2})
+	if err == nil || err.Error() != "load-generating download failed" {
+		t.Fatalf("expected load failure for a 404 download, got %v", err)
+	}
+}
+
+func TestMeasureRejectsIncompleteConfig(t *testing.T) {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+	if _, err := Measure(context.Background(), Options{ConfigURL: srv.URL + "/small", Duration: time.Second}); err == nil {
+		t.Fatal("expected config error")
+	}
+}
+
+func TestTrimmedMeanDropsOutliers(t *testing.T) {
+	ds := make([]time.Duration, 20)
+	for i := range ds {
+		ds[i] = 10 * time.Millisecond
+	}
+	ds[19] = time.Second
+	if got := trimmedMean(ds); got != 10*time.Millisecond {
+		t.Fatalf("expected outlier to be trimmed, got %s", got)
+	}
+}
@@ -0,0 +1,75 @@
+package rpm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Config is the document served at /config, in the format of the IETF
+// responsiveness draft, so clients can also point at other compliant servers.
+type Config struct {
+	Version int `json:"version"`
+	URLs    struct {
+		SmallDownloadURL string `json:"small_https_download_url"`
+		LargeDownloadURL string `json:"large_https_download_url"`
+		UploadURL        string `json:"https_upload_url"`
+	} `json:"urls"`
+}
+
+const largeChunk = 64 << 10
+
+// Handler serves /config, /small (one byte), /large (an endless download) and
+// /slurp (an upload sink).
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base := scheme + "://" + r.Host
+		var c Config
+		c.Version = 1
+		c.URLs.SmallDownloadURL = base + "/small"
+		c.URLs.LargeDownloadURL = base + "/large"
+		c.URLs.UploadURL = base + "/slurp"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c)
+	})
+	mux.HandleFunc("GET /small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write([]byte{'x'})
+	})
+	mux.HandleFunc("GET /large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		buf := make([]byte, largeChunk)
+		rc := http.NewResponseController(w)
+		for r.Context().Err() == nil {
+			if _, err := w.Write(buf); err != nil {
+				return
+			}
+			_ = rc.Flush()
+		}
+	})
+	mux.HandleFunc("POST /slurp", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// NewServer returns a server for Handler that speaks HTTP/1.1, HTTP/2 over
+// TLS and unencrypted HTTP/2, so LAN tests need no certificate.
+func NewServer(addr string) *http.Server {
+	return &http.Server{Addr: addr, Handler: Handler(), Protocols: serverProtocols(), ReadHeaderTimeout: 10 * time.Second}
+}
+
+func serverProtocols() *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
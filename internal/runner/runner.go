@@ -21,6 +21,9 @@ type RunResult struct {
 
 func BuildChecks(cfg config.Config) []checks.Check {
 	all := []checks.Check{checks.LocalCheck{}, checks.SpeedtestCheck{}, checks.IperfCheck{}}
+	if cfg.Bandwidth.NetworkQuality.Enabled {
+		all = append(all, checks.NetworkQualityCheck{})
+	}
 	for _, p := range cfg.Targets.Ping {
 		all = append(all, checks.ReachabilityCheck{Target: p})
 	}
@@ -31,12 +34,24 @@ func BuildChecks(cfg config.Config) []checks.Check {
 		}
 	}
 	for _, u := range cfg.Targets.HTTPURLs {
-		all = append(all, checks.HTTPCheck{URL: u})
+		all = append(all, checks.HTTPCheck{URL: u, Engine: cfg.HTTPEngineFor(u)})
+	}
+	seenTLS := map[string]bool{}
+	for _, u := range cfg.Targets.HTTPURLs {
+		tc := checks.TLSCheck{URL: u}
+		if !strings.HasPrefix(u, "https://") || seenTLS[tc.ID()] {
+			continue
+		}
+		seenTLS[tc.ID()] = true
+		all = append(all, tc)
 	}
 	if len(cfg.Targets.Ping) > 0 {
 		all = append(all, checks.PathCheck{Target: cfg.Targets.Ping[0]})
 		all = append(all, checks.BufferbloatCheck{Target: cfg.Targets.Ping[0]})
 	}
+	if cfg.Responsiveness.URL != "" {
+		all = append(all, checks.ResponsivenessCheck{URL: cfg.Responsiveness.URL})
+	}
 	return all
 }
 
@@ -71,34 +86,29 @@ func SelectedChecks(cfg config.Config, opts model.RunOptions) []checks.Check {
 
 func RunOnce(ctx context.Context, ex execx.Executor, cfg config.Config, opts model.RunOptions, version, commit string) (RunResult, error) {
 	all := SelectedChecks(cfg, opts)
-	res := make([]model.CheckResult, 0, len(all))
+	res := newScheduler(ctx, ex, cfg, opts.FailFast, len(all)).run(PlanStages(all))
 	summary := model.Summary{}
-	for i, c := range all {
-		reportProgress(ctx, ProgressEvent{Phase: "start", Check: c.ID(), Group: c.Group(), Index: i + 1, Total: len(all)})
-		cctx := execx.WithCheckMetadata(ctx, strings.ToUpper(c.Group()), c.ID())
-		cr := c.Run(cctx, ex, cfg, cfg.PerCheckTimeoutSec)
-		reportProgress(ctx, ProgressEvent{Phase: "end", Check: c.ID(), Group: c.Group(), Index: i + 1, Total: len(all), Status: cr.Status})
+	for _, cr := range res {
 		summary.Add(cr.Status)
-		res = append(res, cr)
-		if opts.FailFast && cr.Status == model.StatusFail {
-			break
-		}
 	}
 	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
 	host, _ := os.Hostname()
+	breakdown := eval.Breakdown(res, cfg.Scoring, cfg.Thresholds.Limits)
 	report := model.Report{
-		SchemaVersion: schema.Version,
-		Timestamp:     time.Now().UTC(),
-		Host:          host,
-		OS:            runtime.GOOS,
-		Version:       version,
-		GitCommit:     commit,
-		RunID:         opts.RunID,
-		Labels:        opts.Labels,
-		Config:        cfg.AsMap(),
-		Checks:        res,
-		Summary:       summary,
-		Score:         eval.Score(res),
+		SchemaVersion:  schema.Version,
+		Timestamp:      time.Now().UTC(),
+		Host:           host,
+		OS:             runtime.GOOS,
+		Version:        version,
+		GitCommit:      commit,
+		RunID:          opts.RunID,
+		Labels:         opts.Labels,
+		Config:         cfg.AsMap(),
+		Checks:         res,
+		Summary:        summary,
+		Score:          breakdown.Score,
+		ScoreBreakdown: &breakdown,
+		Scoring:        &cfg.Scoring,
 	}
 	return RunResult{Report: report}, nil
 }
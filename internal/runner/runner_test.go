@@ -7,24 +7,27 @@ import (
 	"netcheck/internal/model"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunOnceFakeExecutor(t *testing.T) {
 	cfg := config.Defaults()
 	cfg.Bandwidth.Speedtest.Enabled = false
 	cfg.Bandwidth.Iperf.Enabled = false
+	cfg.DNS.Engine = "dig"
+	cfg.TLS.Enabled = false
 	cfg.Targets.Resolvers = []string{"1.1.1.1"}
 	fake := &execx.FakeExecutor{
 		Paths: map[string]bool{"netstat": true, "ping": true, "dig": true, "curl": true, "mtr": true},
 		Outputs: map[string]execx.Result{
-			"netstat -rn":             {Stdout: "Routing tables\ndefault 10.0.0.1"},
-			"ping -c 10 10.0.0.1":     {Stdout: samplePing()},
-			"ping -c 10 1.1.1.1":      {Stdout: samplePing()},
-			"ping -c 10 8.8.8.8":      {Stdout: samplePing()},
-			"dig google.com":          {Stdout: ";; Query time: 20 msec"},
-			"dig @1.1.1.1 google.com": {Stdout: ";; Query time: 22 msec"},
-			"curl -w dns:%{time_namelookup} connect:%{time_connect} tls:%{time_appconnect} ttfb:%{time_starttransfer} total:%{time_total} -o /dev/null -s https://example.com": {Stdout: "dns:0.01 connect:0.02 tls:0.03 ttfb:0.04 total:0.10"},
-			"mtr -rwzc 10 1.1.1.1": {Stdout: "HOST: x"},
+			"netstat -rn":                {Stdout: "Routing tables\ndefault 10.0.0.1"},
+			"ping -c 10 -- 10.0.0.1":     {Stdout: samplePing()},
+			"ping -c 10 -- 1.1.1.1":      {Stdout: samplePing()},
+			"ping -c 10 -- 8.8.8.8":      {Stdout: samplePing()},
+			"dig -q google.com":          {Stdout: ";; Query time: 20 msec"},
+			"dig @1.1.1.1 -q google.com": {Stdout: ";; Query time: 22 msec"},
+			"curl -w dns:%{time_namelookup} connect:%{time_connect} tls:%{time_appconnect} ttfb:%{time_starttransfer} total:%{time_total} -o /dev/null -s --url https://example.com": {Stdout: "dns:0.01 connect:0.02 tls:0.03 ttfb:0.04 total:0.10"},
+			"mtr -rwzc 10 -- 1.1.1.1": {Stdout: "HOST: x"},
 		},
 	}
 	r, err := RunOnce(context.Background(), fake, cfg, model.RunOptions{}, "dev", "")
@@ -40,10 +43,11 @@ func TestRunOnceSelectSkip(t *testing.T) {
 	cfg := config.Defaults()
 	cfg.Bandwidth.Speedtest.Enabled = false
 	cfg.Bandwidth.Iperf.Enabled = false
+	cfg.DNS.Engine = "dig"
 	fake := &execx.FakeExecutor{
 		Paths: map[string]bool{"dig": true},
 		Outputs: map[string]execx.Result{
-			"dig google.com": {Stdout: ";; Query time: 20 msec"},
+			"dig -q google.com": {Stdout: ";; Query time: 20 msec"},
 		},
 	}
 	r, err := RunOnce(context.Background(), fake, cfg, model.RunOptions{Select: []string{"dns"}, Skip: []string{"bandwidth"}}, "dev", "")
@@ -55,9 +59,196 @@ func TestRunOnceSelectSkip(t *testing.T) {
 	}
 }
 
+func TestBuildChecksAddsOneTLSCheckPerHTTPSHost(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Targets.HTTPURLs = []string{"https://example.com", "https://example.com/health", "http://plain.example", "https://example.com:8443"}
+	var ids []string
+	for _, c := range BuildChecks(cfg) {
+		if c.Group() == "tls" {
+			ids = append(ids, c.ID())
+		}
+	}
+	if strings.Join(ids, ",") != "tls.example.com,tls.example.com:8443" {
+		t.Fatalf("unexpected tls checks: %v", ids)
+	}
+}
+
+func TestPlanStagesKeepsBandwidthExclusive(t *testing.T) {
+	cfg := config.Defaults()
+	stages := PlanStages(BuildChecks(cfg))
+	var shape []string
+	for _, st := range stages {
+		if st.Exclusive {
+			shape = append(shape, "x:"+st.Checks[0].ID())
+			continue
+		}
+		shape = append(shape, "p:"+st.Checks[0].Group())
+	}
+	want := "p:local,x:bandwidth.speedtest,x:bandwidth.iperf,p:reachability,x:bufferbloat.1.1.1.1"
+	if strings.Join(shape, ",") != want {
+		t.Fatalf("unexpected plan %v", shape)
+	}
+}
+
+func TestRunOnceRunsParallelChecksConcurrently(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Concurrency.Workers = 4
+	cfg.Targets.Ping = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9", "4.4.4.4"}
+	fake := &execx.FakeExecutor{
+		Paths:   map[string]bool{"ping": true},
+		Outputs: map[string]execx.Result{},
+		Delays:  map[string]time.Duration{},
+	}
+	for _, p := range cfg.Targets.Ping {
+		fake.Outputs["ping -c 10 -- "+p] = execx.Result{Stdout: samplePing()}
+		fake.Delays["ping -c 10 -- "+p] = 200 * time.Millisecond
+	}
+	var events []ProgressEvent
+	ctx := WithProgressReporter(context.Background(), func(ev ProgressEvent) { events = append(events, ev) })
+	start := time.Now()
+	r, err := RunOnce(ctx, fake, cfg, model.RunOptions{Select: []string{"reachability"}}, "dev", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 600*time.Millisecond {
+		t.Fatalf("expected parallel execution, took %s", elapsed)
+	}
+	if len(r.Report.Checks) != 4 || r.Report.Checks[0].ID != "reachability.1.1.1.1" {
+		t.Fatalf("unexpected sorted checks: %+v", r.Report.Checks)
+	}
+	var ends []int
+	for _, ev := range events {
+		if ev.Phase == "end" {
+			ends = append(ends, ev.Index)
+		}
+	}
+	if len(ends) != 4 || ends[0] != 1 || ends[1] != 2 || ends[2] != 3 || ends[3] != 4 {
+		t.Fatalf("end events must be released in check order, got %v", ends)
+	}
+}
+
+func TestRunOnceHonorsGroupLimit(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Concurrency.Workers = 4
+	cfg.Concurrency.GroupLimits = map[string]int{"reachability": 1}
+	cfg.Targets.Ping = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+	fake := &execx.FakeExecutor{Paths: map[string]bool{"ping": true}, Outputs: map[string]execx.Result{}, Delays: map[string]time.Duration{}}
+	for _, p := range cfg.Targets.Ping {
+		fake.Outputs["ping -c 10 -- "+p] = execx.Result{Stdout: samplePing()}
+		fake.Delays["ping -c 10 -- "+p] = 100 * time.Millisecond
+	}
+	start := time.Now()
+	if _, err := RunOnce(context.Background(), fake, cfg, model.RunOptions{Select: []string{"reachability"}}, "dev", ""); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("expected group limit to serialize reachability, took %s", elapsed)
+	}
+	if got := strings.Join(fake.Calls, ","); got != "ping -c 10 -- 1.1.1.1,ping -c 10 -- 8.8.8.8,ping -c 10 -- 9.9.9.9" {
+		t.Fatalf("unexpected call order: %s", got)
+	}
+}
+
+func TestRunOnceSkipsChecksBlockedByGateway(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Bandwidth.Speedtest.Enabled = false
+	cfg.Bandwidth.Iperf.Enabled = false
+	cfg.DNS.Engine = "dig"
+	cfg.TLS.Enabled = false
+	cfg.Targets.Ping = []string{"1.1.1.1"}
+	cfg.Targets.Resolvers = []string{"1.1.1.1"}
+	fake := &execx.FakeExecutor{
+		Paths: map[string]bool{"netstat": true, "ping": true, "dig": true, "curl": true},
+		Outputs: map[string]execx.Result{
+			"netstat -rn":            {Stdout: "default 10.0.0.1"},
+			"ping -c 10 -- 10.0.0.1": {Stdout: "10 packets transmitted, 0 packets received, 100.0% packet loss"},
+		},
+	}
+	r, err := RunOnce(context.Background(), fake, cfg, model.RunOptions{}, "dev", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]model.CheckResult{}
+	for _, c := range r.Report.Checks {
+		byID[c.ID] = c
+	}
+	if byID["local.gateway"].Status != model.StatusFail {
+		t.Fatalf("expected gateway failure, got %+v", byID["local.gateway"])
+	}
+	if c := byID["reachability.1.1.1.1"]; c.Status != model.StatusSkip || c.BlockedBy != "local.gateway" {
+		t.Fatalf("expected reachability blocked by gateway, got %+v", c)
+	}
+	if c := byID["dns.google.com@1.1.1.1"]; c.Status != model.StatusSkip || c.BlockedBy != "reachability.1.1.1.1" {
+		t.Fatalf("expected resolver check blocked by reachability, got %+v", c)
+	}
+	if c := byID["bandwidth.speedtest"]; c.BlockedBy != "local.gateway" {
+		t.Fatalf("expected bandwidth blocked by gateway, got %+v", c)
+	}
+	if got := strings.Join(fake.Calls, ","); got != "netstat -rn,ping -c 10 -- 10.0.0.1" {
+		t.Fatalf("blocked checks must not run tools, got calls %s", got)
+	}
+}
+
+func TestPlanStagesSplitsOnDependencies(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Targets.Ping = []string{"1.1.1.1"}
+	cfg.Targets.Resolvers = []string{"1.1.1.1"}
+	stages := PlanStages(SelectedChecks(cfg, model.RunOptions{Select: []string{"local", "reachability", "dns"}}))
+	if len(stages) != 3 {
+		t.Fatalf("expected 3 stages, got %d", len(stages))
+	}
+	if stages[2].Checks[0].ID() != "dns.google.com@1.1.1.1" {
+		t.Fatalf("resolver check must start a new stage after its reachability check, got %s", stages[2].Checks[0].ID())
+	}
+}
+
 func samplePing() string {
 	return strings.Join([]string{
 		"10 packets transmitted, 10 packets received, 0.0% packet loss",
 		"round-trip min/avg/max/stddev = 10.000/20.000/30.000/5.000 ms",
 	}, "\n")
 }
+
+func TestRunOnceUsesAndEchoesScoringModel(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.DNS.Engine = "dig"
+	fake := &execx.FakeExecutor{
+		Paths:   map[string]bool{"dig": true},
+		Outputs: map[string]execx.Result{"dig -q google.com": {Stdout: ";; Query time: 20 msec"}},
+	}
+	opts := model.RunOptions{Select: []string{"dns"}}
+	r, err := RunOnce(context.Background(), fake, cfg, opts, "dev", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Report.Score == 0 || r.Report.Scoring == nil || r.Report.Scoring.Weights["dns"] != 10 {
+		t.Fatalf("expected default scoring to be echoed, got score=%d model=%+v", r.Report.Score, r.Report.Scoring)
+	}
+	cfg.Scoring.Groups["dns"] = ""
+	r, err = RunOnce(context.Background(), fake, cfg, opts, "dev", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Report.Score != 0 || r.Report.Scoring.Category("dns") != "" {
+		t.Fatalf("unscored group should not count, got score=%d", r.Report.Score)
+	}
+}
+
+func TestBuildChecksAddsNetworkQualityWhenEnabled(t *testing.T) {
+	cfg := config.Defaults()
+	has := func() bool {
+		for _, c := range BuildChecks(cfg) {
+			if c.ID() == "bandwidth.networkquality" {
+				return true
+			}
+		}
+		return false
+	}
+	if has() {
+		t.Fatal("networkquality must be opt-in")
+	}
+	cfg.Bandwidth.NetworkQuality.Enabled = true
+	if !has() {
+		t.Fatal("expected bandwidth.networkquality when enabled")
+	}
+}
@@ -0,0 +1,216 @@
+package runner
+
+import (
+	"context"
+	"netcheck/internal/checks"
+	"netcheck/internal/config"
+	"netcheck/internal/execx"
+	"netcheck/internal/model"
+	"strings"
+	"sync"
+)
+
+// exclusiveGroups saturate the link; they run alone so they do not skew each other's measurements.
+var exclusiveGroups = map[string]bool{
+	"bandwidth":      true,
+	"bufferbloat":    true,
+	"responsiveness": true,
+}
+
+func IsExclusive(group string) bool { return exclusiveGroups[group] }
+
+// Stage is a run of consecutive checks that may execute together. An exclusive
+// stage always holds exactly one check.
+type Stage struct {
+	Checks    []checks.Check
+	Exclusive bool
+}
+
+// PlanStages splits checks into stages while preserving their order. A check
+// that depends on another check in the open parallel stage starts a new stage,
+// so prerequisites always finish before their dependents run.
+func PlanStages(all []checks.Check) []Stage {
+	var out []Stage
+	inStage := map[string]bool{}
+	for _, c := range all {
+		if IsExclusive(c.Group()) {
+			out = append(out, Stage{Checks: []checks.Check{c}, Exclusive: true})
+			continue
+		}
+		if n := len(out); n > 0 && !out[n-1].Exclusive && !dependsOnAny(c, inStage) {
+			out[n-1].Checks = append(out[n-1].Checks, c)
+			inStage[c.ID()] = true
+			continue
+		}
+		out = append(out, Stage{Checks: []checks.Check{c}})
+		inStage = map[string]bool{c.ID(): true}
+	}
+	return out
+}
+
+func dependsOnAny(c checks.Check, ids map[string]bool) bool {
+	for _, dep := range checks.DependenciesOf(c) {
+		if ids[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduler runs stages on a bounded worker pool. Start events are emitted in
+// dispatch order and end events are released in check order, so progress stays
+// deterministic regardless of which check finishes first.
+type scheduler struct {
+	ctx      context.Context
+	ex       execx.Executor
+	cfg      config.Config
+	failFast bool
+	total    int
+	workers  chan struct{}
+	groupSem map[string]chan struct{}
+
+	mu      sync.Mutex
+	pending map[int]model.CheckResult
+	byIndex map[int]checks.Check
+	byID    map[string]model.CheckResult
+	nextEnd int
+	out     []model.CheckResult
+	stopped bool
+}
+
+func newScheduler(ctx context.Context, ex execx.Executor, cfg config.Config, failFast bool, total int) *scheduler {
+	workers := cfg.Concurrency.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	s := &scheduler{
+		ctx:      ctx,
+		ex:       ex,
+		cfg:      cfg,
+		failFast: failFast,
+		total:    total,
+		workers:  make(chan struct{}, workers),
+		groupSem: map[string]chan struct{}{},
+		pending:  map[int]model.CheckResult{},
+		byIndex:  map[int]checks.Check{},
+		byID:     map[string]model.CheckResult{},
+	}
+	for g, n := range cfg.Concurrency.GroupLimits {
+		if n > 0 {
+			s.groupSem[g] = make(chan struct{}, n)
+		}
+	}
+	return s
+}
+
+func (s *scheduler) run(stages []Stage) []model.CheckResult {
+	idx := 0
+	for _, st := range stages {
+		if s.isStopped() {
+			break
+		}
+		if st.Exclusive {
+			s.start(idx, st.Checks[0])
+			s.finish(idx, st.Checks[0], s.exec(st.Checks[0]))
+			idx++
+			continue
+		}
+		var wg sync.WaitGroup
+		for _, c := range st.Checks {
+			if s.isStopped() {
+				break
+			}
+			gsem := s.groupSem[c.Group()]
+			if gsem != nil {
+				gsem <- struct{}{}
+			}
+			s.workers <- struct{}{}
+			s.start(idx, c)
+			wg.Add(1)
+			go func(i int, c checks.Check) {
+				defer wg.Done()
+				cr := s.exec(c)
+				<-s.workers
+				if gsem != nil {
+					<-gsem
+				}
+				s.finish(i, c, cr)
+			}(idx, c)
+			idx++
+		}
+		wg.Wait()
+	}
+	s.flush()
+	return s.out
+}
+
+func (s *scheduler) exec(c checks.Check) model.CheckResult {
+	if dep := s.blockedBy(c); dep != "" {
+		return model.CheckResult{ID: c.ID(), Group: c.Group(), Status: model.StatusSkip, Error: "blocked by " + dep, BlockedBy: dep}
+	}
+	cctx := execx.WithCheckMetadata(s.ctx, strings.ToUpper(c.Group()), c.ID())
+	return c.Run(cctx, s.ex, s.cfg, s.cfg.PerCheckTimeoutSec)
+}
+
+func (s *scheduler) start(i int, c checks.Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reportProgress(s.ctx, ProgressEvent{Phase: "start", Check: c.ID(), Group: c.Group(), Index: i + 1, Total: s.total})
+}
+
+func (s *scheduler) finish(i int, c checks.Check, cr model.CheckResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[i] = cr
+	s.byIndex[i] = c
+	s.byID[c.ID()] = cr
+	if s.failFast && cr.Status == model.StatusFail {
+		s.stopped = true
+	}
+	for {
+		next, ok := s.pending[s.nextEnd]
+		if !ok {
+			return
+		}
+		s.release(s.nextEnd, next)
+		s.nextEnd++
+	}
+}
+
+// flush releases results stranded behind checks that were never dispatched after fail-fast.
+func (s *scheduler) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := s.nextEnd; len(s.pending) > 0; i++ {
+		if cr, ok := s.pending[i]; ok {
+			s.release(i, cr)
+		}
+	}
+}
+
+func (s *scheduler) release(i int, cr model.CheckResult) {
+	c := s.byIndex[i]
+	delete(s.pending, i)
+	reportProgress(s.ctx, ProgressEvent{Phase: "end", Check: c.ID(), Group: c.Group(), Index: i + 1, Total: s.total, Status: cr.Status})
+	s.out = append(s.out, cr)
+}
+
+// blockedBy returns the first prerequisite of c that failed or was itself blocked.
+// Prerequisites outside the selected checks are ignored.
+func (s *scheduler) blockedBy(c checks.Check) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, dep := range checks.DependenciesOf(c) {
+		r, ok := s.byID[dep]
+		if ok && (r.Status == model.StatusFail || r.BlockedBy != "") {
+			return dep
+		}
+	}
+	return ""
+}
+
+func (s *scheduler) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
@@ -0,0 +1,217 @@
+package soak
+
+import (
+	"math"
+	"netcheck/internal/model"
+	"sort"
+	"time"
+)
+
+// Aggregator accumulates soak intervals into the run_summary payload.
+type Aggregator struct {
+	intervals int
+	order     []string
+	checks    map[string]*checkAgg
+	worst     *IntervalRef
+}
+
+type checkAgg struct {
+	id, group, target string
+	statuses          map[model.Status]int
+	metrics           map[string][]float64
+	worst             *CheckInterval
+	streak            Streak
+	longest           Streak
+}
+
+// IntervalRef identifies one soak interval by 1-based index and start time.
+type IntervalRef struct {
+	Interval  int           `json:"interval"`
+	Timestamp time.Time     `json:"timestamp"`
+	Score     int           `json:"score"`
+	Summary   model.Summary `json:"summary"`
+}
+
+// CheckInterval is the worst single result observed for a check.
+type CheckInterval struct {
+	Interval  int          `json:"interval"`
+	Timestamp time.Time    `json:"timestamp"`
+	Status    model.Status `json:"status"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// Streak is a run of consecutive failing intervals.
+type Streak struct {
+	Length        int       `json:"length"`
+	FirstInterval int       `json:"first_interval,omitempty"`
+	LastInterval  int       `json:"last_interval,omitempty"`
+	Start         time.Time `json:"start,omitzero"`
+	End           time.Time `json:"end,omitzero"`
+}
+
+type MetricStats struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+type CheckSummary struct {
+	ID                string                 `json:"id"`
+	Group             string                 `json:"group"`
+	Target            string                 `json:"target,omitempty"`
+	Samples           int                    `json:"samples"`
+	Statuses          map[model.Status]int   `json:"statuses"`
+	AvailabilityPct   float64                `json:"availability_pct"`
+	Metrics           map[string]MetricStats `json:"metrics,omitempty"`
+	WorstInterval     *CheckInterval         `json:"worst_interval,omitempty"`
+	LongestFailStreak Streak                 `json:"longest_fail_streak"`
+}
+
+type Summary struct {
+	Intervals     int            `json:"intervals"`
+	WorstInterval *IntervalRef   `json:"worst_interval,omitempty"`
+	Checks        []CheckSummary `json:"checks"`
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{checks: map[string]*checkAgg{}}
+}
+
+// Add records one interval's report.
+func (a *Aggregator) Add(r model.Report) {
+	a.intervals++
+	n := a.intervals
+	if a.worst == nil || r.Score < a.worst.Score {
+		a.worst = &IntervalRef{Interval: n, Timestamp: r.Timestamp, Score: r.Score, Summary: r.Summary}
+	}
+	for _, c := range r.Checks {
+		ca, ok := a.checks[c.ID]
+		if !ok {
+			ca = &checkAgg{id: c.ID, group: c.Group, target: c.Target, statuses: map[model.Status]int{}, metrics: map[string][]float64{}}
+			a.checks[c.ID] = ca
+			a.order = append(a.order, c.ID)
+		}
+		ca.statuses[c.Status]++
+		for k, raw := range c.Metrics {
+			if v, ok := numeric(raw); ok {
+				ca.metrics[k] = append(ca.metrics[k], v)
+			}
+		}
+		if ca.worst == nil || severity(c.Status) > severity(ca.worst.Status) {
+			ca.worst = &CheckInterval{Interval: n, Timestamp: r.Timestamp, Status: c.Status, Error: c.Error}
+		}
+		if c.Status == model.StatusFail {
+			if ca.streak.Length == 0 {
+				ca.streak.FirstInterval = n
+				ca.streak.Start = r.Timestamp
+			}
+			ca.streak.Length++
+			ca.streak.LastInterval = n
+			ca.streak.End = r.Timestamp
+			if ca.streak.Length > ca.longest.Length {
+				ca.longest = ca.streak
+			}
+		} else {
+			ca.streak = Streak{}
+		}
+	}
+}
+
+// Summary returns per-check statistics in first-seen check order.
+func (a *Aggregator) Summary() Summary {
+	out := Summary{Intervals: a.intervals, WorstInterval: a.worst, Checks: make([]CheckSummary, 0, len(a.order))}
+	for _, id := range a.order {
+		ca := a.checks[id]
+		cs := CheckSummary{
+			ID:                ca.id,
+			Group:             ca.group,
+			Target:            ca.target,
+			Statuses:          map[model.Status]int{model.StatusPass: 0, model.StatusWarn: 0, model.StatusFail: 0, model.StatusSkip: 0},
+			WorstInterval:     ca.worst,
+			LongestFailStreak: ca.longest,
+		}
+		for s, n := range ca.statuses {
+			cs.Statuses[s] = n
+			cs.Samples += n
+		}
+		// Skipped intervals say nothing about availability, so they are left out.
+		if measured := cs.Samples - cs.Statuses[model.StatusSkip]; measured > 0 {
+			up := cs.Statuses[model.StatusPass] + cs.Statuses[model.StatusWarn]
+			cs.AvailabilityPct = math.Round(float64(up)/float64(measured)*10000) / 100
+		}
+		if len(ca.metrics) > 0 {
+			cs.Metrics = map[string]MetricStats{}
+			for k, values := range ca.metrics {
+				cs.Metrics[k] = stats(values)
+			}
+		}
+		out.Checks = append(out.Checks, cs)
+	}
+	return out
+}
+
+func stats(values []float64) MetricStats {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return MetricStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Avg:   sum / float64(len(sorted)),
+		P50:   percentile(sorted, 50),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// percentile uses nearest rank over sorted values, matching the check parsers.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func numeric(v any) (float64, bool) {
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func severity(s model.Status) int {
+	switch s {
+	case model.StatusFail:
+		return 3
+	case model.StatusWarn:
+		return 2
+	case model.StatusPass:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package soak
+
+import (
+	"netcheck/internal/model"
+	"testing"
+	"time"
+)
+
+func report(score int, at time.Time, checks ...model.CheckResult) model.Report {
+	r := model.Report{Timestamp: at, Score: score, Checks: checks}
+	for _, c := range checks {
+		r.Summary.Add(c.Status)
+	}
+	return r
+}
+
+func TestAggregatorSummary(t *testing.T) {
+	t0 := time.Unix(1700000000, 0).UTC()
+	a := NewAggregator()
+	statuses := []model.Status{model.StatusPass, model.StatusFail, model.StatusFail, model.StatusWarn, model.StatusFail, model.StatusSkip}
+	for i, st := range statuses {
+		at := t0.Add(time.Duration(i) * time.Minute)
+		a.Add(report(100-10*i, at,
+			model.CheckResult{ID: "dns.example.com", Group: "dns", Status: st, Metrics: map[string]any{"query_ms": float64(10 * (i + 1)), "rcode": "NOERROR", "answer_count": 1}},
+			model.CheckResult{ID: "local.gateway", Group: "local", Status: model.StatusPass},
+		))
+	}
+	s := a.Summary()
+	if s.Intervals != 6 || s.WorstInterval == nil || s.WorstInterval.Interval != 6 || s.WorstInterval.Score != 50 {
+		t.Fatalf("unexpected run totals: %+v %+v", s, s.WorstInterval)
+	}
+	if len(s.Checks) != 2 || s.Checks[0].ID != "dns.example.com" {
+		t.Fatalf("unexpected check order: %+v", s.Checks)
+	}
+	dns := s.Checks[0]
+	if dns.Samples != 6 || dns.Statuses[model.StatusFail] != 3 || dns.Statuses[model.StatusSkip] != 1 {
+		t.Fatalf("unexpected status distribution: %+v", dns.Statuses)
+	}
+	if dns.AvailabilityPct != 40 {
+		t.Fatalf("expected 40%% availability excluding skips, got %v", dns.AvailabilityPct)
+	}
+	q := dns.Metrics["query_ms"]
+	if q.Count != 6 || q.Min != 10 || q.Max != 60 || q.Avg != 35 || q.P50 != 30 || q.P95 != 60 || q.P99 != 60 {
+		t.Fatalf("unexpected query_ms stats: %+v", q)
+	}
+	if _, ok := dns.Metrics["rcode"]; ok {
+		t.Fatal("non-numeric metrics must be ignored")
+	}
+	if dns.WorstInterval == nil || dns.WorstInterval.Interval != 2 || dns.WorstInterval.Status != model.StatusFail {
+		t.Fatalf("unexpected worst interval: %+v", dns.WorstInterval)
+	}
+	streak := dns.LongestFailStreak
+	if streak.Length != 2 || streak.FirstInterval != 2 || streak.LastInterval != 3 || !streak.Start.Equal(t0.Add(time.Minute)) {
+		t.Fatalf("unexpected fail streak: %+v", streak)
+	}
+	gw := s.Checks[1]
+	if gw.AvailabilityPct != 100 || gw.LongestFailStreak.Length != 0 || gw.Metrics != nil {
+		t.Fatalf("unexpected gateway summary: %+v", gw)
+	}
+}
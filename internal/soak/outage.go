@@ -0,0 +1,161 @@
+package soak
+
+import (
+	"math"
+	"netcheck/internal/model"
+	"sort"
+	"time"
+)
+
+const gatewayID = "local.gateway"
+
+// Outage is one window of consecutive intervals in which the network was down:
+// the gateway check failed, or every measured reachability target failed.
+type Outage struct {
+	Cause            string    `json:"cause"`
+	AffectedGroups   []string  `json:"affected_groups"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end,omitzero"`
+	DurationSec      float64   `json:"duration_sec"`
+	FirstInterval    int       `json:"first_interval"`
+	LastInterval     int       `json:"last_interval"`
+	LastGoodInterval int       `json:"last_good_interval,omitempty"`
+	Ongoing          bool      `json:"ongoing,omitempty"`
+}
+
+// OutageSummary is the outage section of the run_summary payload. MTBF is
+// uptime divided by the number of outages; MTTR averages the outages that
+// ended before the run did.
+type OutageSummary struct {
+	Count       int      `json:"count"`
+	DowntimeSec float64  `json:"downtime_sec"`
+	MTBFSec     float64  `json:"mtbf_sec,omitempty"`
+	MTTRSec     float64  `json:"mttr_sec,omitempty"`
+	Outages     []Outage `json:"outages"`
+}
+
+// OutageTracker detects outage windows across soak intervals.
+type OutageTracker struct {
+	intervals int
+	first     time.Time
+	lastGood  int
+	current   *Outage
+	groups    map[string]bool
+	done      []Outage
+}
+
+func NewOutageTracker() *OutageTracker {
+	return &OutageTracker{}
+}
+
+// Observe records one interval. It returns the outage that started in this
+// interval, or the one that ended with it, or neither.
+func (t *OutageTracker) Observe(r model.Report) (started, ended *Outage) {
+	t.intervals++
+	n := t.intervals
+	if n == 1 {
+		t.first = r.Timestamp
+	}
+	cause, measured := outageCause(r)
+	if !measured {
+		return nil, nil
+	}
+	if cause == "" {
+		t.lastGood = n
+		if t.current == nil {
+			return nil, nil
+		}
+		o := *t.current
+		o.End = r.Timestamp
+		o.DurationSec = seconds(o.End.Sub(o.Start))
+		t.done = append(t.done, o)
+		t.current = nil
+		return nil, &o
+	}
+	if t.current == nil {
+		t.current = &Outage{Cause: cause, Start: r.Timestamp, FirstInterval: n, LastGoodInterval: t.lastGood}
+		t.groups = map[string]bool{}
+		started = t.current
+	}
+	t.current.LastInterval = n
+	for _, c := range r.Checks {
+		if c.Status == model.StatusFail || c.BlockedBy != "" {
+			t.groups[c.Group] = true
+		}
+	}
+	t.current.AffectedGroups = sortedSet(t.groups)
+	if started != nil {
+		o := *started
+		return &o, nil
+	}
+	return nil, nil
+}
+
+// Summary totals downtime up to end, counting an unfinished outage as ongoing.
+func (t *OutageTracker) Summary(end time.Time) OutageSummary {
+	out := OutageSummary{Outages: append([]Outage{}, t.done...)}
+	if t.current != nil {
+		o := *t.current
+		o.Ongoing = true
+		o.DurationSec = seconds(end.Sub(o.Start))
+		out.Outages = append(out.Outages, o)
+	}
+	out.Count = len(out.Outages)
+	var repair float64
+	for _, o := range out.Outages {
+		out.DowntimeSec += o.DurationSec
+		if !o.Ongoing {
+			repair += o.DurationSec
+		}
+	}
+	if out.Count == 0 || t.intervals == 0 {
+		return out
+	}
+	if up := seconds(end.Sub(t.first)) - out.DowntimeSec; up > 0 {
+		out.MTBFSec = math.Round(up/float64(out.Count)*1000) / 1000
+	}
+	if len(t.done) > 0 {
+		out.MTTRSec = math.Round(repair/float64(len(t.done))*1000) / 1000
+	}
+	return out
+}
+
+// outageCause reports why an interval counts as down, or "" when it does not.
+// Skipped reachability checks are not measurements, so an interval where all
+// of them were skipped is judged by the gateway alone. measured is false when
+// the interval ran neither, as happens with per-group schedules; such
+// intervals leave the outage state unchanged.
+func outageCause(r model.Report) (cause string, measured bool) {
+	reach, failed := 0, 0
+	for _, c := range r.Checks {
+		if c.ID == gatewayID && c.Status != model.StatusSkip {
+			if c.Status == model.StatusFail {
+				return "gateway", true
+			}
+			measured = true
+		}
+		if c.Group == "reachability" && c.Status != model.StatusSkip {
+			reach++
+			if c.Status == model.StatusFail {
+				failed++
+			}
+		}
+	}
+	if reach > 0 && failed == reach {
+		return "reachability", true
+	}
+	return "", measured || reach > 0
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func seconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
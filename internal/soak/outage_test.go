@@ -0,0 +1,72 @@
+package soak
+
+import (
+	"netcheck/internal/model"
+	"testing"
+	"time"
+)
+
+func interval(at time.Time, gateway model.Status, reach ...model.Status) model.Report {
+	checks := []model.CheckResult{{ID: "local.gateway", Group: "local", Status: gateway}}
+	for i, st := range reach {
+		c := model.CheckResult{ID: "reachability.t" + string(rune('a'+i)), Group: "reachability", Status: st}
+		if gateway == model.StatusFail {
+			c.Status, c.BlockedBy = model.StatusSkip, "local.gateway"
+		}
+		checks = append(checks, c)
+	}
+	checks = append(checks, model.CheckResult{ID: "dns.example.com", Group: "dns", Status: model.StatusPass})
+	return report(100, at, checks...)
+}
+
+func TestOutageTrackerWindows(t *testing.T) {
+	t0 := time.Unix(1700000000, 0).UTC()
+	at := func(i int) time.Time { return t0.Add(time.Duration(i) * time.Minute) }
+	tr := NewOutageTracker()
+	pass, fail := model.StatusPass, model.StatusFail
+
+	if s, e := tr.Observe(interval(at(0), pass, pass, fail)); s != nil || e != nil {
+		t.Fatal("one failing target out of two is not an outage")
+	}
+	s, _ := tr.Observe(interval(at(1), pass, fail, fail))
+	if s == nil || s.Cause != "reachability" || s.FirstInterval != 2 || s.LastGoodInterval != 1 || s.Start != at(1) {
+		t.Fatalf("unexpected outage start: %+v", s)
+	}
+	if s, e := tr.Observe(interval(at(2), fail, pass, pass)); s != nil || e != nil {
+		t.Fatal("a continuing outage must not emit events")
+	}
+	dnsOnly := report(100, at(3), model.CheckResult{ID: "dns.example.com", Group: "dns", Status: model.StatusPass})
+	if s, e := tr.Observe(dnsOnly); s != nil || e != nil {
+		t.Fatal("an interval without gateway or reachability checks must not end an outage")
+	}
+	_, e := tr.Observe(interval(at(4), pass, pass, pass))
+	if e == nil || e.DurationSec != 180 || e.LastInterval != 3 || e.End != at(4) {
+		t.Fatalf("unexpected outage end: %+v", e)
+	}
+	if len(e.AffectedGroups) != 2 || e.AffectedGroups[0] != "local" || e.AffectedGroups[1] != "reachability" {
+		t.Fatalf("unexpected affected groups: %v", e.AffectedGroups)
+	}
+
+	s, _ = tr.Observe(interval(at(6), fail))
+	if s == nil || s.Cause != "gateway" || s.LastGoodInterval != 5 {
+		t.Fatalf("unexpected gateway outage: %+v", s)
+	}
+	sum := tr.Summary(at(7))
+	if sum.Count != 2 || !sum.Outages[1].Ongoing || sum.Outages[1].DurationSec != 60 {
+		t.Fatalf("unexpected outages: %+v", sum.Outages)
+	}
+	// 7 minutes observed, 4 of them down: MTBF 180s/2, MTTR from the ended outage only.
+	if sum.DowntimeSec != 240 || sum.MTBFSec != 90 || sum.MTTRSec != 180 {
+		t.Fatalf("unexpected totals: %+v", sum)
+	}
+}
+
+func TestOutageTrackerNoOutages(t *testing.T) {
+	tr := NewOutageTracker()
+	t0 := time.Unix(1700000000, 0).UTC()
+	tr.Observe(interval(t0, model.StatusPass, model.StatusSkip))
+	sum := tr.Summary(t0.Add(time.Minute))
+	if sum.Count != 0 || sum.DowntimeSec != 0 || sum.MTBFSec != 0 || sum.Outages == nil {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+}
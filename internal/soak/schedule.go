@@ -0,0 +1,56 @@
+package soak
+
+import (
+	"sort"
+	"time"
+)
+
+// Schedule tracks when each check group is next due. Groups without their
+// own interval run every default interval.
+type Schedule struct {
+	every map[string]time.Duration
+	next  map[string]time.Time
+}
+
+func NewSchedule(start time.Time, groups []string, def time.Duration, per map[string]int) *Schedule {
+	s := &Schedule{every: map[string]time.Duration{}, next: map[string]time.Time{}}
+	for _, g := range groups {
+		d := def
+		if sec, ok := per[g]; ok && sec > 0 {
+			d = time.Duration(sec) * time.Second
+		}
+		s.every[g] = d
+		s.next[g] = start
+	}
+	return s
+}
+
+// Due returns the sorted groups due at now and moves each one to its next
+// slot after now. Slots missed while a slow window ran are dropped rather
+// than run back to back.
+func (s *Schedule) Due(now time.Time) []string {
+	var out []string
+	for g, at := range s.next {
+		if at.After(now) {
+			continue
+		}
+		out = append(out, g)
+		for !at.After(now) {
+			at = at.Add(s.every[g])
+		}
+		s.next[g] = at
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Next returns the earliest time any group is due.
+func (s *Schedule) Next() time.Time {
+	var first time.Time
+	for _, at := range s.next {
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+	}
+	return first
+}
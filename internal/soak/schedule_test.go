@@ -0,0 +1,34 @@
+package soak
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScheduleDue(t *testing.T) {
+	t0 := time.Unix(1700000000, 0).UTC()
+	s := NewSchedule(t0, []string{"bandwidth", "dns", "reachability"}, 5*time.Second, map[string]int{"dns": 30, "bandwidth": 3600})
+	if got := s.Due(t0); !slices.Equal(got, []string{"bandwidth", "dns", "reachability"}) {
+		t.Fatalf("every group is due at start, got %v", got)
+	}
+	if next := s.Next(); !next.Equal(t0.Add(5 * time.Second)) {
+		t.Fatalf("unexpected next window %v", next)
+	}
+	counts := map[string]int{}
+	for now := t0.Add(5 * time.Second); now.Before(t0.Add(time.Minute)); now = s.Next() {
+		for _, g := range s.Due(now) {
+			counts[g]++
+		}
+	}
+	if counts["reachability"] != 11 || counts["dns"] != 1 || counts["bandwidth"] != 0 {
+		t.Fatalf("unexpected run counts in the first minute: %v", counts)
+	}
+	// A 20s window overran three reachability slots; they are not replayed.
+	if got := s.Due(t0.Add(80 * time.Second)); !slices.Equal(got, []string{"dns", "reachability"}) {
+		t.Fatalf("unexpected groups after overrun: %v", got)
+	}
+	if next := s.Next(); !next.Equal(t0.Add(85 * time.Second)) {
+		t.Fatalf("unexpected next window after overrun %v", next)
+	}
+}